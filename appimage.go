@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// appImage is an AppImage installed by 'i'.
+type appImage struct {
+	Source  string `json:"source"`  // URL, owner/repo of a GitHub release, or the file it was installed from
+	Version string `json:"version"` // release tag, or the start of the sha256 of the file for other sources
+	File    string `json:"file"`
+	Desktop string `json:"desktop,omitempty"`
+	Icon    string `json:"icon,omitempty"`
+}
+
+// appImageRegistryFile records the installed AppImages by name
+const appImageRegistryFile = "appimages.json"
+
+func readAppImageRegistry() map[string]appImage {
+	registry := map[string]appImage{}
+	readState(appImageRegistryFile, &registry)
+	return registry
+}
+
+// appImageDir is the managed directory the AppImages and their icons are kept in.
+func appImageDir() (string, error) {
+	dir, err := dataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "appimages"), nil
+}
+
+// runAppImage runs the appimage manager commands: install (a URL, owner/repo
+// or a file), uninstall, upgrade (one or all), info and list.
+func runAppImage(action, arg string) error {
+	switch action {
+	case "install":
+		return installAppImage(arg, "")
+	case "uninstall":
+		return uninstallAppImage(appImageKey(arg))
+	case "upgrade":
+		if arg != "" {
+			return upgradeAppImage(appImageKey(arg))
+		}
+		var failed []string
+		for _, name := range slices.Sorted(maps.Keys(readAppImageRegistry())) {
+			if err := upgradeAppImage(name); err != nil {
+				fmt.Printf("[error] upgrading %s: %v\n", name, err)
+				failed = append(failed, name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("could not upgrade %s", strings.Join(failed, ", "))
+		}
+		return nil
+	case "info":
+		arg = appImageKey(arg)
+		app, ok := readAppImageRegistry()[arg]
+		if !ok {
+			return fmt.Errorf("%s is not installed with appimage", arg)
+		}
+		fmt.Printf("name:    %s\nsource:  %s\nversion: %s\nfile:    %s\n", arg, app.Source, app.Version, app.File)
+		if app.Desktop != "" {
+			fmt.Printf("desktop: %s\n", app.Desktop)
+		}
+		return nil
+	case "list":
+		registry := readAppImageRegistry()
+		for _, name := range slices.Sorted(maps.Keys(registry)) {
+			fmt.Printf("%s %s (%s)\n", name, registry[name].Version, registry[name].Source)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown appimage command: %s", action)
+}
+
+// appImageKey returns the registry name of an AppImage given by its name or
+// by the source it was installed from, e.g. owner/repo or a URL.
+func appImageKey(arg string) string {
+	registry := readAppImageRegistry()
+	if _, ok := registry[arg]; ok {
+		return arg
+	}
+	for name, app := range registry {
+		if app.Source == arg {
+			return name
+		}
+	}
+	return arg
+}
+
+// appImageVersionSuffix is the version and architecture part of AppImage file names
+var appImageVersionSuffix = regexp.MustCompile(`[-_. ]v?[0-9].*$|[-_.](x86_64|amd64|aarch64|arm64|x64|linux).*$`)
+
+// appImageName makes the name of an AppImage from its file name, without
+// the version and architecture: Obsidian-1.4.16.AppImage -> obsidian.
+func appImageName(file string) string {
+	base := filepath.Base(file)
+	base = base[:len(base)-len(filepath.Ext(base))]
+	name := appImageVersionSuffix.ReplaceAllString(base, "")
+	if name == "" {
+		name = base
+	}
+	return strings.ToLower(name)
+}
+
+// installAppImage installs an AppImage from source, installed is the version
+// already installed ("" for none) to skip unchanged upgrades.
+func installAppImage(source, installed string) error {
+	tmpDir, err := os.MkdirTemp("", "i-appimage-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var file, name, version string
+	switch {
+	case isPackageURL(source):
+		file = filepath.Join(tmpDir, "download.AppImage")
+		if err := downloadTo(source, file); err != nil {
+			return err
+		}
+		name = appImageName(source)
+	case matchesNamePattern("github", source):
+		release, err := latestRelease(source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		version = release.TagName
+		if version == installed {
+			fmt.Printf("%s is up to date (%s)\n", source, installed)
+			return nil
+		}
+		i := pickAppImageAsset(release, runtime.GOARCH)
+		if i < 0 {
+			return fmt.Errorf("the %s release of %s has no AppImage for %s", release.TagName, source, runtime.GOARCH)
+		}
+		file = filepath.Join(tmpDir, release.Assets[i].Name)
+		if err := downloadTo(release.Assets[i].DownloadURL, file); err != nil {
+			return err
+		}
+		_, name = filepath.Split(source)
+		name = strings.ToLower(name)
+	default:
+		file = source
+		name = appImageName(source)
+	}
+
+	if err := checkAppImage(file); err != nil {
+		return err
+	}
+	if version == "" {
+		sum, err := fileSHA256(file)
+		if err != nil {
+			return err
+		}
+		version = sum[:12]
+		if version == installed {
+			fmt.Printf("%s is up to date\n", name)
+			return nil
+		}
+	}
+
+	dir, err := appImageDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(dir, name+".AppImage")
+	// remove first, a running AppImage can not be overwritten
+	os.Remove(dest)
+	if err := copyFile(file, dest); err != nil {
+		return err
+	}
+	if err := os.Chmod(dest, 0755); err != nil {
+		return err
+	}
+
+	app := appImage{Source: source, Version: version, File: dest}
+	if desktop, icon, err := integrateAppImage(name, dest, tmpDir); err != nil {
+		fmt.Printf("[warn] no menu entry for %s: %v\n", name, err)
+	} else {
+		app.Desktop, app.Icon = desktop, icon
+	}
+
+	registry := readAppImageRegistry()
+	registry[name] = app
+	if err := writeState(appImageRegistryFile, registry); err != nil {
+		return err
+	}
+	// uninstall and upgrade find it by its name
+	recordInstall(name, "appimage")
+	if !quiet {
+		fmt.Printf("[info] installed %s as %s (%s)\n", name, dest, version)
+	}
+	return nil
+}
+
+func upgradeAppImage(name string) error {
+	app, ok := readAppImageRegistry()[name]
+	if !ok {
+		return fmt.Errorf("%s is not installed with appimage", name)
+	}
+	if !isPackageURL(app.Source) && !matchesNamePattern("github", app.Source) {
+		fmt.Printf("%s was installed from the file %s, install a new file to upgrade it\n", name, app.Source)
+		return nil
+	}
+	return installAppImage(app.Source, app.Version)
+}
+
+func uninstallAppImage(name string) error {
+	registry := readAppImageRegistry()
+	app, ok := registry[name]
+	if !ok {
+		return fmt.Errorf("%s is not installed with appimage", name)
+	}
+	for _, f := range []string{app.File, app.Desktop, app.Icon} {
+		if f == "" {
+			continue
+		}
+		if err := os.Remove(f); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	delete(registry, name)
+	// older versions recorded the source too
+	forgetInstall(name)
+	forgetInstall(app.Source)
+	if app.Desktop != "" {
+		updateDesktopDatabase(filepath.Dir(app.Desktop))
+	}
+	if !quiet {
+		fmt.Printf("[info] removed %s\n", app.File)
+	}
+	return writeState(appImageRegistryFile, registry)
+}
+
+// pickAppImageAsset returns the index of the AppImage asset of a release for
+// goarch, AppImages without an architecture in the name are x86_64.
+func pickAppImageAsset(release Release, goarch string) int {
+	fallback := -1
+	for i, a := range release.Assets {
+		lower := strings.ToLower(a.Name)
+		if !strings.HasSuffix(lower, ".appimage") {
+			continue
+		}
+		words := strings.FieldsFunc(lower, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+		switch assetArch(lower, words) {
+		case goarch:
+			return i
+		case "":
+			if goarch == "amd64" && fallback < 0 {
+				fallback = i
+			}
+		}
+	}
+	return fallback
+}
+
+// checkAppImage checks that file is an ELF executable with the AppImage magic bytes.
+func checkAppImage(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	head := make([]byte, 11)
+	if _, err := io.ReadFull(f, head); err != nil || !bytes.HasPrefix(head, []byte("\x7fELF")) {
+		return fmt.Errorf("%s is not an AppImage", filepath.Base(file))
+	}
+	// type 1 and 2 AppImages have "AI" and the type at offset 8
+	if !bytes.Equal(head[8:10], []byte("AI")) {
+		return fmt.Errorf("%s is an executable, but not an AppImage", filepath.Base(file))
+	}
+	return nil
+}
+
+func downloadTo(url, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		fmt.Printf("[info] downloading %s\n", url)
+	}
+	err = downloadFile(url, f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// integrateAppImage writes a .desktop entry for the AppImage, made from the
+// one inside it, and copies its icon next to it. It returns both paths.
+func integrateAppImage(name, appImagePath, tmpDir string) (string, string, error) {
+	dataHome, err := xdgDataHome()
+	if err != nil {
+		return "", "", err
+	}
+
+	extractDir := filepath.Join(tmpDir, "squashfs-root")
+	extractAppImage(appImagePath, extractDir)
+
+	var entry []string
+	if desktops, _ := filepath.Glob(filepath.Join(extractDir, "*.desktop")); len(desktops) > 0 {
+		data, err := os.ReadFile(desktops[0])
+		if err != nil {
+			return "", "", err
+		}
+		entry = strings.Split(string(data), "\n")
+	} else {
+		entry = []string{"[Desktop Entry]", "Type=Application", "Name=" + name, "Exec=x", "Categories=Utility;"}
+	}
+
+	icon := ""
+	if src, err := filepath.EvalSymlinks(filepath.Join(extractDir, ".DirIcon")); err == nil && strings.HasPrefix(src, extractDir) {
+		ext := filepath.Ext(src)
+		if ext == "" || ext == ".DirIcon" {
+			ext = ".png"
+		}
+		icon = strings.TrimSuffix(appImagePath, ".AppImage") + ext
+		if err := copyFile(src, icon); err != nil {
+			icon = ""
+		}
+	}
+
+	desktop := filepath.Join(dataHome, "applications", "i-"+name+".desktop")
+	if err := os.MkdirAll(filepath.Dir(desktop), 0755); err != nil {
+		return "", "", err
+	}
+	out := rewriteDesktopEntry(entry, appImagePath, icon)
+	if err := os.WriteFile(desktop, []byte(strings.Join(out, "\n")), 0644); err != nil {
+		return "", "", err
+	}
+	updateDesktopDatabase(filepath.Dir(desktop))
+	return desktop, icon, nil
+}
+
+// appImageExtractTimeout bounds the extraction, an AppImage that ignores
+// --appimage-extract starts the app instead
+const appImageExtractTimeout = time.Minute
+
+// extractAppImage unpacks the files of an AppImage into dir: type 2 AppImages
+// with unsquashfs, without running them, others with their --appimage-extract.
+func extractAppImage(appImagePath, dir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), appImageExtractTimeout)
+	defer cancel()
+
+	if offset, err := appImageOffset(appImagePath); err == nil {
+		if ok, _ := isInstalled("unsquashfs"); ok {
+			return exec.CommandContext(ctx, "unsquashfs", "-no-progress", "-o", strconv.FormatInt(offset, 10), "-d", dir, appImagePath).Run()
+		}
+	}
+	cmd := exec.CommandContext(ctx, appImagePath, "--appimage-extract")
+	cmd.Dir = filepath.Dir(dir)
+	return cmd.Run()
+}
+
+// appImageOffset returns where the squashfs of a type 2 AppImage starts: after
+// the ELF runtime, whose section headers come last.
+func appImageOffset(file string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	head := make([]byte, 64)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return 0, err
+	}
+	return squashfsOffset(f, head)
+}
+
+// squashfsOffset reads the end of the ELF file from its header and checks
+// that a squashfs starts there.
+func squashfsOffset(r io.ReaderAt, head []byte) (int64, error) {
+	if !bytes.HasPrefix(head, []byte("\x7fELF")) || len(head) < 64 {
+		return 0, fmt.Errorf("not an ELF file")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if head[5] == 2 {
+		order = binary.BigEndian
+	}
+	var offset int64
+	switch head[4] {
+	case 1: // 32 bit
+		offset = int64(order.Uint32(head[0x20:])) + int64(order.Uint16(head[0x2e:]))*int64(order.Uint16(head[0x30:]))
+	case 2: // 64 bit
+		offset = int64(order.Uint64(head[0x28:])) + int64(order.Uint16(head[0x3a:]))*int64(order.Uint16(head[0x3c:]))
+	default:
+		return 0, fmt.Errorf("unknown ELF class %d", head[4])
+	}
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, offset); err != nil || string(magic) != "hsqs" {
+		return 0, fmt.Errorf("no squashfs at offset %d", offset)
+	}
+	return offset, nil
+}
+
+// rewriteDesktopEntry makes the lines of the .desktop file of an AppImage run
+// the installed AppImage and use its copied icon ("" for none).
+func rewriteDesktopEntry(entry []string, appImagePath, icon string) []string {
+	var out []string
+	inEntry, hasIcon := false, false
+	for _, line := range entry {
+		if strings.HasPrefix(line, "[") {
+			inEntry = strings.TrimSpace(line) == "[Desktop Entry]"
+		}
+		key, value, _ := strings.Cut(line, "=")
+		switch {
+		case key == "Exec":
+			// keep the arguments (e.g. %U), run the installed AppImage
+			args := strings.Fields(value)
+			line = "Exec=" + strings.Join(append([]string{desktopExecArg(appImagePath)}, args[min(1, len(args)):]...), " ")
+		case key == "TryExec":
+			continue
+		case key == "Icon" && inEntry:
+			hasIcon = true
+			if icon != "" {
+				line = "Icon=" + icon
+			}
+		}
+		out = append(out, line)
+	}
+	if icon != "" && !hasIcon {
+		if i := slices.IndexFunc(out, func(l string) bool { return strings.TrimSpace(l) == "[Desktop Entry]" }); i >= 0 {
+			out = slices.Insert(out, i+1, "Icon="+icon)
+		}
+	}
+	return out
+}
+
+// desktopExecArg quotes an argument of the Exec key as the Desktop Entry
+// specification wants: in double quotes with \", \`, \$ and \\ escaped when it
+// has reserved characters, then with the backslashes of the string value doubled.
+func desktopExecArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range arg {
+		if strings.ContainsRune("\"`$\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return strings.ReplaceAll(b.String(), `\`, `\\`)
+}
+
+// updateDesktopDatabase refreshes the menu cache, if the tool is installed.
+func updateDesktopDatabase(dir string) {
+	if ok, _ := isInstalled("update-desktop-database"); ok {
+		exec.Command("update-desktop-database", dir).Run()
+	}
+}
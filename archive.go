@@ -0,0 +1,180 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// extractArchive unpacks a tar (gzip, bzip2, xz or zstd compressed) or zip
+// archive into dir, a gzip compressed or plain file is copied there as name.
+func extractArchive(file, name, dir string) error {
+	lower := strings.ToLower(file)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return extractZip(file, dir)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return extractTar(file, dir, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) })
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz"):
+		return extractTar(file, dir, func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil })
+	case strings.HasSuffix(lower, ".tar"):
+		return extractTar(file, dir, func(r io.Reader) (io.Reader, error) { return r, nil })
+	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"), strings.HasSuffix(lower, ".tar.zst"):
+		// no xz and zstd in the standard library, tar has them
+		if out, err := exec.Command("tar", "-xf", file, "-C", dir).CombinedOutput(); err != nil {
+			return fmt.Errorf("tar failed: %v\n%s", err, out)
+		}
+		return nil
+	case strings.HasSuffix(lower, ".gz"):
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		r, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(dir, name), r, 0755)
+	default:
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return writeFile(filepath.Join(dir, name), src, 0755)
+	}
+}
+
+func extractTar(file, dir string, decompress func(io.Reader) (io.Reader, error)) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	r, err := decompress(src)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// only regular files, links could point out of dir
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		dest, err := archivePath(dir, h.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(dest, tr, fs.FileMode(h.Mode).Perm()); err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(file, dir string) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		dest, err := archivePath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(dest, rc, f.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePath joins an archive entry name to dir, refusing names that leave dir.
+func archivePath(dir, name string) (string, error) {
+	dest := filepath.Join(dir, name)
+	if !strings.HasPrefix(dest, filepath.Clean(dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %s is outside of the archive", name)
+	}
+	return dest, nil
+}
+
+func writeFile(path string, r io.Reader, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// executableMagic are the first bytes of ELF, Mach-O (32, 64 bit and universal) and Windows executables
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe},
+	{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+	[]byte("MZ"),
+}
+
+// findExecutables returns the native executables under dir, shared libraries
+// (ELF or Mach-O too) are skipped by name.
+func findExecutables(dir string) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.Contains(d.Name(), ".so") || strings.HasSuffix(d.Name(), ".dylib") || strings.HasSuffix(d.Name(), ".dll") {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		head := make([]byte, 4)
+		n, _ := io.ReadFull(f, head)
+		f.Close()
+		for _, m := range executableMagic {
+			if bytes.HasPrefix(head[:n], m) {
+				found = append(found, path)
+				break
+			}
+		}
+		return nil
+	})
+	return found, err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// runBuiltin runs a command template implemented by 'i', for built-in package
+// managers and commands others lack, e.g. "@github install sharkdp/bat", "@cargo upgrade".
+func runBuiltin(cmdStr string) error {
+	parts := strings.Fields(cmdStr)
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid command: %s", cmdStr)
+	}
+	arg := ""
+	if len(parts) > 2 {
+		arg = parts[2]
+	}
+	switch parts[0] {
+	case "@github":
+		return runGithub(parts[1], arg)
+	case "@appimage":
+		return runAppImage(parts[1], arg)
+	case "@cargo":
+		return runCargo(parts[1])
+	case "@go":
+		return runGo(parts[1], arg)
+	case "@mise":
+		return runMise(parts[1])
+	case "@asdf":
+		return runAsdf(parts[1], arg)
+	case "@sdkman":
+		return runSdkman(parts[1], arg)
+	}
+	return fmt.Errorf("unknown package manager: %s", strings.TrimPrefix(parts[0], "@"))
+}
+
+// builtinHasPackages reports whether a package manager implemented by 'i' installed anything.
+func builtinHasPackages(name string) bool {
+	switch name {
+	case "github":
+		return len(readGithubRegistry()) > 0
+	case "appimage":
+		return len(readAppImageRegistry()) > 0
+	}
+	return false
+}
+
+// addBuiltinPMs adds the package managers implemented by 'i' that installed
+// packages to detectedPMs, after the primary one is chosen, so list and
+// upgrade cover them but they are never used for new packages by default.
+func addBuiltinPMs() {
+	if forcedPM != "" {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(pm_commands)) {
+		if pm_commands[name].Builtin && builtinHasPackages(name) {
+			detectedPMs = append(detectedPMs, packageManager{Name: name})
+		}
+	}
+}
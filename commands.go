@@ -2,6 +2,9 @@ package main
 
 type commands struct {
 	Name          string
+	Probes        []string // executables that show the manager is installed, the first found is used
+	OS            []string // runtime.GOOS values the manager runs on
+	Priority      int      // execution priority, 1 for system package managers, 2 for the others
 	Install       string
 	Uninstall     string
 	Upgrade       string
@@ -10,6 +13,14 @@ type commands struct {
 	UpgradeAll    string
 	ListInstalled string
 	UpdateIndex   string
+	Available     string // exits with 0 only if the package exists in the repositories, used to fall back to another manager
+	NeedsReboot   bool   // changes are applied to a new deployment on the next boot
+	VersionFormat string // writes a package name and version to install that version (e.g. "%s=%s"), "" if versions can not be pinned
+	InstallFile   string // installs a local package file, x is its absolute path
+	NamePattern   string // package names this manager takes that others do not (e.g. owner/repo), they select it
+	Builtin       bool   // implemented by 'i' itself, templates start with @name, detected when it has installed packages
+	UserLevel     bool   // installs tools for the user (language package managers), never uses sudo and is never the primary one
+	Runtimes      bool   // manages versions of language runtimes (e.g. node@20) next to the ones of the system
 }
 
 var pm_commands = map[string]commands{
@@ -23,8 +34,38 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "i upgrade",
 		ListInstalled: "i list",
 	},
+	"github": { // binaries from GitHub releases, installed into the user bin dir, no sudo
+		Name:          "github",
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      3,
+		Install:       "@github install x",
+		Uninstall:     "@github uninstall x",
+		Upgrade:       "@github upgrade x",
+		Info:          "@github info x",
+		UpgradeAll:    "@github upgrade",
+		ListInstalled: "@github list",
+		NamePattern:   `^[A-Za-z0-9][A-Za-z0-9-]*/[A-Za-z0-9_.-]+$`, // owners have no dots, unlike the hosts of Go modules
+		Builtin:       true,
+	},
+	"appimage": { // AppImages with menu entries, kept in ~/.local/share/i/appimages, no sudo
+		Name:          "appimage",
+		OS:            []string{"linux"},
+		Priority:      3,
+		Install:       "@appimage install x",
+		Uninstall:     "@appimage uninstall x",
+		Upgrade:       "@appimage upgrade x",
+		Info:          "@appimage info x",
+		UpgradeAll:    "@appimage upgrade",
+		ListInstalled: "@appimage list",
+		InstallFile:   "@appimage install x",
+		NamePattern:   `(?i)^https?://\S+\.appimage$`,
+		Builtin:       true,
+	},
 	"apt": { // needs sudo for install, remove, upgrade, update
 		Name:          "apt",
+		Probes:        []string{"apt"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo apt install x",
 		Uninstall:     "sudo apt remove x",
 		Upgrade:       "sudo apt install --only-upgrade x",
@@ -33,9 +74,15 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo apt upgrade",
 		ListInstalled: "apt list --installed", // apt list -i
 		UpdateIndex:   "sudo apt update",
+		Available:     "apt-cache show x",
+		VersionFormat: "%s=%s",
+		InstallFile:   "sudo apt install -y x",
 	},
 	"brew": { // no need for sudo AT ALL
 		Name:          "brew",
+		Probes:        []string{"brew"},
+		OS:            []string{"darwin", "linux"},
+		Priority:      1,
 		Install:       "brew install x",
 		Uninstall:     "brew uninstall x",
 		Upgrade:       "brew upgrade x",
@@ -44,9 +91,14 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "brew upgrade",
 		ListInstalled: "brew list",
 		UpdateIndex:   "brew update",
+		Available:     "brew info x",
+		VersionFormat: "%s@%s",
 	},
 	"port": { // needs sudo for install, remove, upgrade, update
 		Name:          "port",
+		Probes:        []string{"port"},
+		OS:            []string{"darwin"},
+		Priority:      1,
 		Install:       "sudo port install x",
 		Uninstall:     "sudo port uninstall x",
 		Upgrade:       "sudo port upgrade x",
@@ -54,9 +106,13 @@ var pm_commands = map[string]commands{
 		Info:          "port info x",
 		UpgradeAll:    "sudo port upgrade",
 		ListInstalled: "port installed",
+		Available:     "port info x",
 	},
 	"flatpak": { // if system-wide, need sudo for install, remove, upgrade, update
 		Name:          "flatpak",
+		Probes:        []string{"flatpak"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo flatpak install x",
 		Uninstall:     "sudo flatpak uninstall x",
 		Upgrade:       "sudo flatpak update x",
@@ -64,9 +120,13 @@ var pm_commands = map[string]commands{
 		Info:          "flatpak info x",
 		UpgradeAll:    "sudo flatpak update",
 		ListInstalled: "flatpak list",
+		InstallFile:   "sudo flatpak install -y --from x",
 	},
 	"snap": { // need sudo for install, remove, upgrade, update
 		Name:          "snap",
+		Probes:        []string{"snap"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo snap install --classic x", // --classic or not ?
 		Uninstall:     "sudo snap remove x",
 		Upgrade:       "sudo snap refresh x",
@@ -74,9 +134,14 @@ var pm_commands = map[string]commands{
 		Info:          "snap info x",
 		UpgradeAll:    "sudo snap refresh",
 		ListInstalled: "snap list",
+		Available:     "snap info x",
+		VersionFormat: "%s --channel=%s",
 	},
 	"dnf": { // need sudo for install, remove, upgrade, update
 		Name:          "dnf",
+		Probes:        []string{"dnf"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo dnf install -y x",
 		Uninstall:     "sudo dnf remove -y x",
 		Upgrade:       "sudo dnf upgrade -y x",
@@ -85,19 +150,29 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo dnf upgrade -y",
 		ListInstalled: "dnf list installed",
 		UpdateIndex:   "dnf check-update",
+		Available:     "dnf info -q x",
+		VersionFormat: "%s-%s",
+		InstallFile:   "sudo dnf install -y x",
 	},
 	"rpm": { // need sudo for install, remove, upgrade, update
 		Name:          "rpm",
+		Probes:        []string{"rpm"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo rpm -i x",
 		Uninstall:     "sudo rpm -e x",
 		Upgrade:       "sudo rpm -U x",
 		Search:        "rpm -q x",
 		Info:          "rpm -q x",
-		UpgradeAll:    "sudo rpm -Uvh x",
+		UpgradeAll:    "", // rpm has no repositories to upgrade from, dnf/yum/zypper do it
 		ListInstalled: "rpm -qa",
+		InstallFile:   "sudo rpm -i x",
 	},
 	"pacman": { // need sudo for install, remove, upgrade, update
 		Name:          "pacman",
+		Probes:        []string{"pacman"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo pacman -S --noconfirm x",
 		Uninstall:     "sudo pacman -Rs --noconfirm x",
 		Upgrade:       "sudo pacman -Syu --noconfirm x", // Upgrade specific pkg and system? Usually just -S to reinstall/upgrade specific
@@ -106,9 +181,14 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo pacman -Syu --noconfirm",
 		ListInstalled: "pacman -Q",
 		UpdateIndex:   "sudo pacman -Sy",
+		Available:     "pacman -Si x",
+		InstallFile:   "sudo pacman -U --noconfirm x",
 	},
 	"yum": { // need sudo for install, remove, upgrade, update
 		Name:          "yum",
+		Probes:        []string{"yum"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo yum install -y x",
 		Uninstall:     "sudo yum remove -y x",
 		Upgrade:       "sudo yum update -y x",
@@ -117,9 +197,15 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo yum update -y",
 		ListInstalled: "yum list installed",
 		UpdateIndex:   "sudo yum makecache",
+		Available:     "yum info -q x",
+		VersionFormat: "%s-%s",
+		InstallFile:   "sudo yum install -y x",
 	},
 	"zypper": { // needs sudo for install, remove, upgrade, update
 		Name:          "zypper",
+		Probes:        []string{"zypper"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo zypper install -n x",
 		Uninstall:     "sudo zypper remove -n x",
 		Upgrade:       "sudo zypper update -n x",
@@ -128,9 +214,15 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo zypper update -n",
 		ListInstalled: "zypper se --installed-only",
 		UpdateIndex:   "sudo zypper refresh",
+		Available:     "zypper -q search --match-exact x",
+		VersionFormat: "%s=%s",
+		InstallFile:   "sudo zypper install -n x",
 	},
 	"apk": { // needs sudo for install, remove, upgrade, update
 		Name:          "apk",
+		Probes:        []string{"apk"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo apk add x",
 		Uninstall:     "sudo apk del x",
 		Upgrade:       "sudo apk add --upgrade x",
@@ -139,9 +231,14 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo apk upgrade",
 		ListInstalled: "apk info",
 		UpdateIndex:   "sudo apk update",
+		VersionFormat: "%s=%s",
+		InstallFile:   "sudo apk add --allow-untrusted x",
 	},
 	"xbps": { // needs sudo for install, remove, upgrade, update
 		Name:          "xbps",
+		Probes:        []string{"xbps-install"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo xbps-install -y x",
 		Uninstall:     "sudo xbps-remove -y x",
 		Upgrade:       "sudo xbps-install -u x",
@@ -150,9 +247,14 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo xbps-install -Suy",
 		ListInstalled: "xbps-query -l",
 		UpdateIndex:   "sudo xbps-install -S",
+		Available:     "xbps-query -R x",
+		VersionFormat: "%s-%s",
 	},
 	"emerge": { // needs sudo for install, remove, upgrade, update
 		Name:          "emerge",
+		Probes:        []string{"emerge"},
+		OS:            []string{"linux"},
+		Priority:      1,
 		Install:       "sudo emerge x",
 		Uninstall:     "sudo emerge -C x",
 		Upgrade:       "sudo emerge -u x",
@@ -161,9 +263,13 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo emerge -uDN @world",
 		ListInstalled: "qlist -I", // needs portage-utils potentially
 		UpdateIndex:   "sudo emerge --sync",
+		VersionFormat: "=%s-%s",
 	},
 	"nix-env": { // no need for sudo
 		Name:          "nix-env",
+		Probes:        []string{"nix-env"},
+		OS:            []string{"linux", "darwin"},
+		Priority:      1,
 		Install:       "nix-env -iA nixpkgs.x",
 		Uninstall:     "nix-env -e x",
 		Upgrade:       "nix-env -u x",
@@ -172,9 +278,14 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "nix-env -u",
 		ListInstalled: "nix-env -q",
 		UpdateIndex:   "nix-channel --update", // or nix-env -u without args? usually channel update is needed
+		Available:     "nix-env -qaA nixpkgs.x",
+		VersionFormat: "%s_%s",
 	},
 	"pkg": { // needs sudo for install, remove, upgrade, update
 		Name:          "pkg",
+		Probes:        []string{"pkg"},
+		OS:            []string{"freebsd", "dragonfly", "android"},
+		Priority:      2,
 		Install:       "sudo pkg install -y x",
 		Uninstall:     "sudo pkg delete -y x",
 		Upgrade:       "sudo pkg upgrade -y x",
@@ -185,6 +296,9 @@ var pm_commands = map[string]commands{
 	},
 	"winget": { // no need for 'administrator privileges' as MS Windows shows a popup if it needs
 		Name:          "winget",
+		Probes:        []string{"winget"},
+		OS:            []string{"windows"},
+		Priority:      2,
 		Install:       "winget install x",
 		Uninstall:     "winget uninstall x",
 		Upgrade:       "winget upgrade x",
@@ -192,9 +306,14 @@ var pm_commands = map[string]commands{
 		Info:          "winget show x",
 		UpgradeAll:    "winget upgrade",
 		ListInstalled: "winget list",
+		Available:     "winget show --exact x",
+		VersionFormat: "%s --version %s",
 	},
 	"scoop": { // no need for 'administrator privileges'
 		Name:          "scoop",
+		Probes:        []string{"scoop"},
+		OS:            []string{"windows"},
+		Priority:      2,
 		Install:       "scoop install x",
 		Uninstall:     "scoop uninstall x",
 		Upgrade:       "scoop update x",
@@ -202,9 +321,13 @@ var pm_commands = map[string]commands{
 		Info:          "scoop info x",
 		UpgradeAll:    "scoop update",
 		ListInstalled: "scoop list",
+		VersionFormat: "%s@%s",
 	},
 	"choco": { // no need for 'administrator privileges' as MS Windows shows a popup if it needs
 		Name:          "choco",
+		Probes:        []string{"choco"},
+		OS:            []string{"windows"},
+		Priority:      2,
 		Install:       "choco install x",
 		Uninstall:     "choco uninstall x",
 		Upgrade:       "choco upgrade x",
@@ -212,9 +335,13 @@ var pm_commands = map[string]commands{
 		Info:          "choco info x",
 		UpgradeAll:    "choco upgrade",
 		ListInstalled: "choco list",
+		VersionFormat: "%s --version %s",
 	},
 	"urpm": { // needs sudo for urpmi, urpme
 		Name:          "urpm",
+		Probes:        []string{"urpmi"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo urpmi x",
 		Uninstall:     "sudo urpme x",
 		Upgrade:       "sudo urpmi --update x",
@@ -225,6 +352,9 @@ var pm_commands = map[string]commands{
 	},
 	"slackpkg": { // requires sudo for install, remove, upgrade, update
 		Name:          "slackpkg",
+		Probes:        []string{"slackpkg"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo slackpkg install x",
 		Uninstall:     "sudo slackpkg remove x",
 		Upgrade:       "sudo slackpkg upgrade x",
@@ -235,6 +365,9 @@ var pm_commands = map[string]commands{
 	},
 	"prt-get": { // requires sudo for install, remove, upgrade, update
 		Name:          "prt-get",
+		Probes:        []string{"prt-get"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo prt-get install x",
 		Uninstall:     "sudo prt-get remove x",
 		Upgrade:       "sudo prt-get upgrade x",
@@ -245,6 +378,9 @@ var pm_commands = map[string]commands{
 	},
 	"pkgman": { // no need for sudo
 		Name:          "pkgman",
+		Probes:        []string{"pkgman"},
+		OS:            []string{"haiku"}, // not a GOOS of the official Go ports yet
+		Priority:      2,
 		Install:       "pkgman -S x",
 		Uninstall:     "pkgman -R x",
 		Upgrade:       "pkgman -Syu x",
@@ -255,6 +391,9 @@ var pm_commands = map[string]commands{
 	},
 	"opkg": { // requires sudo for install, remove, upgrade, update
 		Name:          "opkg",
+		Probes:        []string{"opkg"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo opkg install x",
 		Uninstall:     "sudo opkg remove x",
 		Upgrade:       "sudo opkg upgrade x",
@@ -265,6 +404,9 @@ var pm_commands = map[string]commands{
 	},
 	"eopkg": { // requires sudo for install, remove, upgrade, update
 		Name:          "eopkg",
+		Probes:        []string{"eopkg"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo eopkg install x",
 		Uninstall:     "sudo eopkg remove x",
 		Upgrade:       "sudo eopkg upgrade x",
@@ -275,6 +417,9 @@ var pm_commands = map[string]commands{
 	},
 	"guix": { // no need for sudo
 		Name:          "guix",
+		Probes:        []string{"guix"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "guix install x",
 		Uninstall:     "guix remove x",
 		Upgrade:       "guix upgrade x",
@@ -282,9 +427,61 @@ var pm_commands = map[string]commands{
 		Info:          "guix info x",
 		UpgradeAll:    "guix upgrade",
 		ListInstalled: "guix list",
+		VersionFormat: "%s@%s",
+	},
+	"swupd": { // Clear Linux, needs sudo for bundle-add, bundle-remove, update
+		Name:          "swupd",
+		Probes:        []string{"swupd"},
+		OS:            []string{"linux"},
+		Priority:      1,
+		Install:       "sudo swupd bundle-add x",
+		Uninstall:     "sudo swupd bundle-remove x",
+		Upgrade:       "sudo swupd update", // bundles are not upgraded one by one
+		Search:        "swupd search x",
+		Info:          "swupd bundle-info x",
+		UpgradeAll:    "sudo swupd update",
+		ListInstalled: "swupd bundle-list",
+		Available:     "swupd bundle-info x",
+	},
+	"rpm-ostree": { // Fedora Atomic desktops, CoreOS; uses polkit, no need for sudo
+		Name:          "rpm-ostree",
+		Probes:        []string{"rpm-ostree"},
+		OS:            []string{"linux"},
+		Priority:      1,
+		Install:       "rpm-ostree install x",
+		Uninstall:     "rpm-ostree uninstall x",
+		Upgrade:       "rpm-ostree upgrade", // the whole image is upgraded at once
+		Search:        "rpm-ostree search x",
+		Info:          "rpm -qi x",
+		UpgradeAll:    "rpm-ostree upgrade",
+		ListInstalled: "rpm-ostree status",
+		UpdateIndex:   "rpm-ostree refresh-md",
+		NeedsReboot:   true,
+		VersionFormat: "%s-%s",
+		InstallFile:   "rpm-ostree install x",
+	},
+	"transactional-update": { // openSUSE MicroOS, Aeon, Kalpa, SL Micro; requires sudo
+		Name:          "transactional-update",
+		Probes:        []string{"transactional-update"},
+		OS:            []string{"linux"},
+		Priority:      1,
+		Install:       "sudo transactional-update -n pkg install x",
+		Uninstall:     "sudo transactional-update -n pkg remove x",
+		Upgrade:       "sudo transactional-update -n pkg update x",
+		Search:        "zypper search x",
+		Info:          "zypper info x",
+		UpgradeAll:    "sudo transactional-update -n dup",
+		ListInstalled: "zypper se --installed-only",
+		NeedsReboot:   true,
+		Available:     "zypper -q search --match-exact x",
+		VersionFormat: "%s=%s",
+		InstallFile:   "sudo transactional-update -n pkg install x",
 	},
 	"cards": { // requires sudo for install, remove, upgrade, update
 		Name:          "cards",
+		Probes:        []string{"cards"},
+		OS:            []string{"linux"},
+		Priority:      2,
 		Install:       "sudo cards install x",
 		Uninstall:     "sudo cards remove x",
 		Upgrade:       "sudo cards upgrade x",
@@ -293,4 +490,137 @@ var pm_commands = map[string]commands{
 		UpgradeAll:    "sudo cards upgrade",
 		ListInstalled: "cards list",
 	},
+	"pipx": { // user-level, Python applications in their own virtual environments
+		Name:          "pipx",
+		Probes:        []string{"pipx"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "pipx install x",
+		Uninstall:     "pipx uninstall x",
+		Upgrade:       "pipx upgrade x",
+		UpgradeAll:    "pipx upgrade-all",
+		ListInstalled: "pipx list --short",
+		VersionFormat: "%s==%s",
+		UserLevel:     true,
+	},
+	"uv": { // user-level, 'uv tool' manages Python applications like pipx
+		Name:          "uv",
+		Probes:        []string{"uv"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "uv tool install x",
+		Uninstall:     "uv tool uninstall x",
+		Upgrade:       "uv tool upgrade x",
+		UpgradeAll:    "uv tool upgrade --all",
+		ListInstalled: "uv tool list",
+		VersionFormat: "%s==%s",
+		UserLevel:     true,
+	},
+	"cargo": { // user-level, installs into ~/.cargo/bin
+		Name:          "cargo",
+		Probes:        []string{"cargo"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "cargo install x",
+		Uninstall:     "cargo uninstall x",
+		Upgrade:       "cargo install x", // reinstalls only if a newer version exists
+		Search:        "cargo search x",
+		Info:          "cargo info x",
+		UpgradeAll:    "@cargo upgrade", // cargo has no upgrade-all, 'i' reinstalls each crate
+		ListInstalled: "cargo install --list",
+		VersionFormat: "%s --version %s",
+		UserLevel:     true,
+	},
+	"go": { // user-level, installs into $GOBIN or ~/go/bin, packages are module paths
+		Name:          "go",
+		Probes:        []string{"go"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "@go install x", // adds @latest
+		Uninstall:     "@go uninstall x",
+		Upgrade:       "@go install x",
+		Info:          "go list -m -versions x",
+		UpgradeAll:    "@go upgrade",
+		ListInstalled: "@go list",
+		NamePattern:   `^[a-z0-9.-]+\.[a-z]+/[A-Za-z0-9_./~-]+(@[A-Za-z0-9_.+-]+)?$`, // golang.org/x/tools/gopls@latest
+		UserLevel:     true,
+	},
+	"npm": { // user-level, global packages of Node.js
+		Name:          "npm",
+		Probes:        []string{"npm"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "npm install -g x",
+		Uninstall:     "npm uninstall -g x",
+		Upgrade:       "npm update -g x",
+		Search:        "npm search x",
+		Info:          "npm view x",
+		UpgradeAll:    "npm update -g",
+		ListInstalled: "npm ls -g --depth=0",
+		VersionFormat: "%s@%s",
+		NamePattern:   `^@[a-z0-9-~][a-z0-9-._~]*/[a-z0-9-~][a-z0-9-._~]*(@[A-Za-z0-9_.+-]+)?$`, // scoped packages, @angular/cli@17
+		UserLevel:     true,
+	},
+	"gem": { // user-level, Ruby gems in the user's gem directory
+		Name:          "gem",
+		Probes:        []string{"gem"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "gem install --user-install x",
+		Uninstall:     "gem uninstall -x x",
+		Upgrade:       "gem update --user-install x",
+		Search:        "gem search x",
+		Info:          "gem info x",
+		UpgradeAll:    "gem update --user-install",
+		ListInstalled: "gem list",
+		VersionFormat: "%s -v %s",
+		UserLevel:     true,
+	},
+	"mise": { // user-level, versions of language runtimes and tools, e.g. node@20
+		Name:          "mise",
+		Probes:        []string{"mise"},
+		OS:            []string{"linux", "darwin", "windows", "freebsd"},
+		Priority:      4,
+		Install:       "mise use -g x", // installs it and makes it the global default
+		Uninstall:     "mise unuse -g x",
+		Upgrade:       "mise upgrade x",
+		Search:        "mise search x",
+		Info:          "mise ls-remote x",
+		UpgradeAll:    "@mise upgrade", // updates the plugins first
+		ListInstalled: "mise ls",
+		VersionFormat: "%s@%s",
+		UserLevel:     true,
+		Runtimes:      true,
+	},
+	"asdf": { // user-level, versions of language runtimes through plugins
+		Name:          "asdf",
+		Probes:        []string{"asdf"},
+		OS:            []string{"linux", "darwin", "freebsd"},
+		Priority:      4,
+		Install:       "@asdf install x", // adds the plugin and makes the version the global default
+		Uninstall:     "@asdf uninstall x",
+		Upgrade:       "@asdf install x",
+		Info:          "asdf list all x",
+		UpgradeAll:    "asdf plugin update --all",
+		ListInstalled: "asdf list",
+		VersionFormat: "%s@%s",
+		UserLevel:     true,
+		Runtimes:      true,
+	},
+	"sdkman": { // user-level, JVM runtimes and tools, sdk is a shell function of its init script
+		Name:          "sdkman",
+		Probes:        []string{"$SDKMAN_DIR/bin/sdkman-init.sh", "~/.sdkman/bin/sdkman-init.sh"},
+		OS:            []string{"linux", "darwin", "freebsd"},
+		Priority:      4,
+		Install:       "@sdkman install x",
+		Uninstall:     "@sdkman uninstall x",
+		Upgrade:       "@sdkman upgrade x",
+		Search:        "@sdkman list",
+		Info:          "@sdkman list x",
+		UpgradeAll:    "@sdkman upgrade", // updates the list of candidates first
+		ListInstalled: "@sdkman current",
+		VersionFormat: "%s@%s",
+		UserLevel:     true,
+		Runtimes:      true,
+	},
 }
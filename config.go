@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// config is read from config.json in the user config directory
+// (e.g. ~/.config/i/config.json on Linux), every field is optional.
+type config struct {
+	// UpdateCheckInterval is how often to look for a new 'i' release, as a Go duration (e.g. "24h"), "0" disables it
+	UpdateCheckInterval string `json:"update_check_interval"`
+	// Sudo is the privilege escalation tool: sudo, doas, run0, pkexec, su or none, I_SUDO overrides it
+	Sudo string `json:"sudo"`
+	// Priorities overrides the execution priority of package managers, e.g. {"brew": 0}, lower is preferred
+	Priorities map[string]int `json:"priorities"`
+	// Rules choose or avoid package managers per package for install, e.g. {"match": "node*", "use": "brew"},
+	// {"gui": true, "use": "flatpak"} for desktop apps (flatpak apps that use a display)
+	Rules []routingRule `json:"rules"`
+}
+
+var cfg config
+
+// configDir is the directory of 'i' config files, e.g. $XDG_CONFIG_HOME/i
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "i"), nil
+}
+
+// cacheDir is the directory of 'i' cache files, e.g. $XDG_CACHE_HOME/i
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "i"), nil
+}
+
+// stateDir is the directory of data 'i' keeps between runs, $XDG_STATE_HOME/i or ~/.local/state/i
+func stateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "i"), nil
+	}
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "i", "state"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "i"), nil
+}
+
+// xdgDataHome is $XDG_DATA_HOME or ~/.local/share, where desktop entries and icons of the user go
+func xdgDataHome() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// dataDir is the directory of files 'i' installs for the user, e.g. $XDG_DATA_HOME/i
+func dataDir() (string, error) {
+	dir, err := xdgDataHome()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "i"), nil
+}
+
+func loadConfig() {
+	dir, err := configDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, "config.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return // no config file, use the defaults
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		fmt.Printf("[warn] ignoring invalid config file %s: %v\n", path, err)
+		cfg = config{}
+	}
+}
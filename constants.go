@@ -3,7 +3,8 @@ package main
 const version = "260203"
 
 var distro_pm = map[string]string{
-	"clearlinux": "swupd",
+	"clearlinux":     "swupd",
+	"clear-linux-os": "swupd", // the actual ID in Clear Linux os-release
 
 	// Debian-based => apt, apt-get
 	"ubuntu":     "apt",
@@ -59,6 +60,7 @@ var distro_pm = map[string]string{
 	"opensuse":            "zypper",
 	"opensuse-leap":       "zypper",
 	"opensuse-tumbleweed": "zypper",
+	"suse":                "zypper", // ID_LIKE of openSUSE and SLES
 	// nix
 	"nixos": "nix-env",
 	// emerge
@@ -87,5 +89,7 @@ var distro_pm = map[string]string{
 
 	"haiku": "pkgman",
 
-	"windows": "winget",
+	"windows":   "winget",
+	"darwin":    "brew",
+	"dragonfly": "pkg",
 }
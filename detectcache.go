@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// detectionCache stores the detected package managers with the state they
+// were detected in, any change to PATH, to a PATH directory (a binary added or
+// removed), to a detected binary, to a probed file outside of PATH, to
+// os-release, to the environment (Termux, systemd) or to 'i' itself invalidates it.
+type detectionCache struct {
+	OS        string           `json:"os"`
+	Path      string           `json:"path"`
+	Env       environment      `json:"env"`
+	ModTimes  map[string]int64 `json:"mod_times"` // PATH directories, detected binaries and os-release
+	Immutable bool             `json:"immutable"`
+	SystemPM  string           `json:"system_pm"`
+	PMs       []packageManager `json:"pms"`
+}
+
+func detectionCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "detect.json"), nil
+}
+
+// watchedFiles are the files and directories whose changes invalidate the detection.
+func watchedFiles(pms []packageManager) []string {
+	files := append([]string{}, osReleasePaths...)
+	files = append(files, ostreeBootedPath)
+	// a new 'i' binary may detect differently
+	if exe, err := os.Executable(); err == nil {
+		files = append(files, exe)
+	}
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir != "" {
+			files = append(files, dir)
+		}
+	}
+	for _, p := range pms {
+		if p.Path != "" {
+			files = append(files, p.Path)
+		}
+	}
+	// probes outside of PATH (e.g. the init script of sdkman), installed or not
+	for _, name := range slices.Sorted(maps.Keys(pm_commands)) {
+		for _, probe := range pm_commands[name].Probes {
+			if !strings.ContainsRune(probe, '/') {
+				continue
+			}
+			if path, ok := expandPath(probe); ok && !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+	return files
+}
+
+// modTime is the modification time of path in nanoseconds, or 0 when it does not exist.
+func modTime(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// loadDetectionCache returns the cached detection if it is still valid.
+func loadDetectionCache() (detectionCache, bool) {
+	var c detectionCache
+	path, err := detectionCachePath()
+	if err != nil {
+		return c, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, false
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, false
+	}
+
+	if c.OS != runtime.GOOS || c.Path != os.Getenv("PATH") || c.Env != runningEnv {
+		return c, false
+	}
+	for _, f := range watchedFiles(c.PMs) {
+		if modTime(f) != c.ModTimes[f] {
+			return c, false
+		}
+	}
+	return c, true
+}
+
+func saveDetectionCache(pms []packageManager) {
+	path, err := detectionCachePath()
+	if err != nil {
+		return
+	}
+
+	c := detectionCache{
+		OS:        runtime.GOOS,
+		Path:      os.Getenv("PATH"),
+		Env:       runningEnv,
+		ModTimes:  make(map[string]int64),
+		Immutable: immutableSystem,
+		SystemPM:  systemPMName,
+		PMs:       pms,
+	}
+	for _, f := range watchedFiles(pms) {
+		c.ModTimes[f] = modTime(f)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// write and rename, so a concurrent run never reads half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), "detect-*.json")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	tmp.Close()
+	if err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// downgrader lists the versions of a package a package manager can still
+// install, from its repositories or its cache, and installs one of them.
+type downgrader struct {
+	versions func(pkg string) []string // newest first
+	install  func(pkg, version string) error
+}
+
+// pacmanCache keeps the packages pacman downloaded, older versions included
+const pacmanCache = "/var/cache/pacman/pkg"
+
+var downgraders = map[string]downgrader{
+	"apt": {
+		versions: func(pkg string) []string {
+			return parseAptVersionTable(commandOutput("apt-cache policy x", pkg))
+		},
+		install: func(pkg, version string) error {
+			return runCommand("sudo apt install --allow-downgrades -y x", pkg+"="+version)
+		},
+	},
+	"dnf": {
+		versions: func(pkg string) []string {
+			return parseDnfList(commandOutput("dnf --showduplicates list -q x", pkg), pkg)
+		},
+		install: func(pkg, version string) error {
+			return runCommand(rpmVersionCommand("dnf", rpmEpochVersion(pkg), version), pkg+"-"+version)
+		},
+	},
+	"yum": {
+		versions: func(pkg string) []string {
+			return parseDnfList(commandOutput("yum --showduplicates list -q x", pkg), pkg)
+		},
+		install: func(pkg, version string) error {
+			return runCommand(rpmVersionCommand("yum", rpmEpochVersion(pkg), version), pkg+"-"+version)
+		},
+	},
+	"zypper": {
+		versions: func(pkg string) []string {
+			return parseZypperVersions(commandOutput("zypper -q search -s --match-exact x", pkg), pkg)
+		},
+		install: func(pkg, version string) error {
+			return runCommand("sudo zypper install -n --oldpackage x", pkg+"="+version)
+		},
+	},
+	"pacman": {
+		versions: func(pkg string) []string {
+			var versions []string
+			for _, file := range pacmanCachedFiles(pkg) {
+				versions = append(versions, file.version)
+			}
+			return versions
+		},
+		install: func(pkg, version string) error {
+			for _, file := range pacmanCachedFiles(pkg) {
+				if file.version == version {
+					return runCommand("sudo pacman -U --noconfirm x", file.path)
+				}
+			}
+			return fmt.Errorf("version %s of '%s' is not in %s", version, pkg, pacmanCache)
+		},
+	},
+	"apk": {
+		versions: func(pkg string) []string {
+			return parseApkPolicy(commandOutput("apk policy x", pkg))
+		},
+		install: func(pkg, version string) error {
+			return runCommand("sudo apk add x", pkg+"="+version)
+		},
+	},
+	"snap": {
+		// snap keeps the previous revisions of a snap, disabled
+		versions: func(pkg string) []string {
+			var versions []string
+			for _, r := range snapRevisions(pkg) {
+				versions = append(versions, r.version)
+			}
+			return versions
+		},
+		install: func(pkg, version string) error {
+			for _, r := range snapRevisions(pkg) {
+				if r.version == version {
+					return runCommand("sudo snap revert x", pkg+" --revision="+r.revision)
+				}
+			}
+			return fmt.Errorf("snap keeps no revision of '%s' with version %s", pkg, version)
+		},
+	},
+}
+
+// downgrade installs an older (or any other available) version of pkg with
+// the package manager that has it installed, asking which one if version is "".
+func downgrade(pkg, version string) {
+	pmName, c := downgradeManager(pkg)
+	d, ok := downgraders[pmName]
+	if !ok {
+		fmt.Printf("Downgrading packages is not supported for %s.\n", pmName)
+		os.Exit(1)
+	}
+	name := translateName(pkg, pmName)
+
+	if version == "" {
+		versions := d.versions(name)
+		if len(versions) == 0 {
+			fmt.Printf("No versions of '%s' found in %s.\n", name, pmName)
+			os.Exit(1)
+		}
+
+		installed := ""
+		if query, ok := versionQueries[pmName]; ok {
+			installed = query(name).Installed
+		}
+		fmt.Printf("versions of '%s' in %s:\n", name, pmName)
+		for i, v := range versions {
+			note := ""
+			if v == installed {
+				note = " (installed)"
+			}
+			fmt.Printf("  %d) %s%s\n", i+1, v, note)
+		}
+
+		version = chooseVersion(versions)
+		if version == "" {
+			fmt.Println("No version chosen.")
+			return
+		}
+		if version == installed {
+			fmt.Printf("Version %s of '%s' is already installed.\n", version, name)
+			return
+		}
+	}
+
+	if err := d.install(name, version); err != nil {
+		fmt.Printf("[error] %v\n", err)
+		os.Exit(1)
+	}
+	if !quiet {
+		fmt.Printf("[info] '%s' is at version %s now, the next 'i upgrade' upgrades it again\n", name, version)
+	}
+	warnAfterChange(c)
+}
+
+// downgradeManager returns the package manager that has pkg installed: the
+// one that installed it through 'i', else the first detected one (the primary
+// first) whose installed version is known, else the primary one.
+func downgradeManager(pkg string) (string, commands) {
+	if forcedPM != "" || installedWith(pkg) != "" {
+		return managerFor(pkg)
+	}
+	for i, p := range append([]packageManager{pm}, detectedPMs...) {
+		_, ok := downgraders[p.Name]
+		query, hasQuery := versionQueries[p.Name]
+		if !ok || !hasQuery || i > 0 && p.Name == pm.Name {
+			continue
+		}
+		if v := query(translateName(pkg, p.Name)).Installed; v != "" && v != unknownVersion {
+			if p.Name != pm.Name && !quiet {
+				fmt.Printf("[info] '%s' is installed with %s, using it\n", pkg, p.Name)
+			}
+			return p.Name, pm_commands[p.Name]
+		}
+	}
+	return managerFor(pkg)
+}
+
+// chooseVersion asks for a version by its number or by itself, "" means none.
+func chooseVersion(versions []string) string {
+	fmt.Print("Version to install (number or version, empty to cancel): ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(versions) {
+		return versions[n-1]
+	}
+	if slices.Contains(versions, answer) {
+		return answer
+	}
+	if answer != "" {
+		fmt.Printf("'%s' is not one of the versions.\n", answer)
+	}
+	return ""
+}
+
+// parseAptVersionTable reads the versions of the "Version table" of
+// 'apt-cache policy', the installed one is marked with ***.
+func parseAptVersionTable(out string) []string {
+	var versions []string
+	inTable := false
+	for line := range strings.SplitSeq(out, "\n") {
+		if strings.TrimSpace(line) == "Version table:" {
+			inTable = true
+			continue
+		}
+		if !inTable {
+			continue
+		}
+		// version lines are " *** 8.7.0-3 500" or "     8.7.0-3 500",
+		// their sources "        500 http://deb.debian.org/debian bookworm/main amd64 Packages"
+		f := strings.Fields(line)
+		if len(f) == 3 && f[0] == "***" {
+			f = f[1:]
+		}
+		if len(f) == 2 {
+			if _, err := strconv.Atoi(f[1]); err == nil {
+				versions = append(versions, f[0])
+			}
+		}
+	}
+	return versions
+}
+
+// rpmVersionCommand is the dnf or yum command that installs version of a package:
+// downgrade refuses a version that is not older than the installed one.
+func rpmVersionCommand(pmName, installed, version string) string {
+	if installed != "" && vercmp(version, installed) < 0 {
+		return "sudo " + pmName + " downgrade -y x"
+	}
+	return "sudo " + pmName + " install -y x"
+}
+
+// rpmEpochVersion is the installed epoch:version-release of pkg, like the
+// versions dnf lists, without the epoch if it has none.
+func rpmEpochVersion(pkg string) string {
+	return strings.TrimPrefix(commandOutput("rpm -q --qf %{EPOCH}:%{VERSION}-%{RELEASE} x", pkg), "(none):")
+}
+
+// parseDnfList reads 'dnf --showduplicates list' lines like
+// "fd-find.x86_64   10.2.0-1.fc40   updates", which are listed oldest first.
+func parseDnfList(out, pkg string) []string {
+	var versions []string
+	for line := range strings.SplitSeq(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 || !strings.HasPrefix(f[0], pkg+".") {
+			continue
+		}
+		if !slices.Contains(versions, f[1]) {
+			versions = append(versions, f[1])
+		}
+	}
+	slices.Reverse(versions)
+	return versions
+}
+
+// parseZypperVersions reads the table of 'zypper search -s', e.g.
+// "v | fd | package | 10.2.0-1.1 | x86_64 | openSUSE-Tumbleweed-Oss".
+func parseZypperVersions(out, pkg string) []string {
+	var versions []string
+	for line := range strings.SplitSeq(out, "\n") {
+		f := strings.Split(line, "|")
+		if len(f) < 4 || strings.TrimSpace(f[1]) != pkg || strings.TrimSpace(f[2]) != "package" {
+			continue
+		}
+		if v := strings.TrimSpace(f[3]); !slices.Contains(versions, v) {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// parseApkPolicy reads the versions of 'apk policy', lines like "  10.2.0-r0:".
+func parseApkPolicy(out string) []string {
+	var versions []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "    ") && strings.HasSuffix(line, ":") {
+			versions = append(versions, strings.TrimSuffix(strings.TrimSpace(line), ":"))
+		}
+	}
+	slices.Reverse(versions)
+	return versions
+}
+
+type pacmanFile struct {
+	path    string
+	version string
+}
+
+// pacmanCachedFiles finds the cached packages of pkg, named like
+// fd-10.2.0-1-x86_64.pkg.tar.zst, newest first.
+func pacmanCachedFiles(pkg string) []pacmanFile {
+	paths, _ := filepath.Glob(filepath.Join(pacmanCache, pkg+"-*.pkg.tar.*"))
+	return parsePacmanFiles(paths, pkg)
+}
+
+// parsePacmanFiles reads the versions of the package files of pkg and sorts them newest first.
+func parsePacmanFiles(paths []string, pkg string) []pacmanFile {
+	var files []pacmanFile
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".sig") {
+			continue
+		}
+		base, _, _ := strings.Cut(filepath.Base(path), ".pkg.tar.")
+		rest := strings.TrimPrefix(base, pkg+"-")
+		// rest is version-release-arch, packages named pkg-something do not start with a digit
+		i := strings.LastIndex(rest, "-")
+		if i <= 0 || rest[0] < '0' || rest[0] > '9' {
+			continue
+		}
+		files = append(files, pacmanFile{path: path, version: rest[:i]})
+	}
+	slices.SortStableFunc(files, func(a, b pacmanFile) int { return vercmp(b.version, a.version) })
+	return files
+}
+
+// vercmp compares two [epoch:]version[-release] strings like pacman's vercmp,
+// it returns -1 if a is older than b, 0 if they are equal and 1 if a is newer.
+func vercmp(a, b string) int {
+	epochA, versionA, releaseA := splitEVR(a)
+	epochB, versionB, releaseB := splitEVR(b)
+	if c := rpmvercmp(epochA, epochB); c != 0 {
+		return c
+	}
+	if c := rpmvercmp(versionA, versionB); c != 0 {
+		return c
+	}
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+	return rpmvercmp(releaseA, releaseB)
+}
+
+func splitEVR(s string) (epoch, version, release string) {
+	epoch = "0"
+	if e, rest, ok := strings.Cut(s, ":"); ok && e != "" && strings.Trim(e, "0123456789") == "" {
+		epoch, s = e, rest
+	}
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		s, release = s[:i], s[i+1:]
+	}
+	return epoch, s, release
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isAlpha(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+// rpmvercmp compares versions segment by segment: numbers numerically, letters
+// alphabetically, a number is newer than letters and a trailing letter segment is
+// older than none (1.0rc1 < 1.0 < 1.0.1), as in rpm and pacman.
+func rpmvercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		si, sj := i, j
+		for i < len(a) && !isDigit(a[i]) && !isAlpha(a[i]) {
+			i++
+		}
+		for j < len(b) && !isDigit(b[j]) && !isAlpha(b[j]) {
+			j++
+		}
+		if i == len(a) || j == len(b) {
+			i, j = si, sj
+			break
+		}
+		// more separators between the same segments is newer
+		if i-si != j-sj {
+			return cmp.Compare(i-si, j-sj)
+		}
+
+		si, sj = i, j
+		isNum := isDigit(a[i])
+		if isNum {
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+		} else {
+			for i < len(a) && isAlpha(a[i]) {
+				i++
+			}
+			for j < len(b) && isAlpha(b[j]) {
+				j++
+			}
+		}
+		segA, segB := a[si:i], b[sj:j]
+		if segB == "" {
+			// different kinds of segments, numbers are newer
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+		if isNum {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return cmp.Compare(len(segA), len(segB))
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	restA, restB := a[i:], b[j:]
+	if restA == "" && restB == "" {
+		return 0
+	}
+	// a remaining letter segment never beats nothing: 1.0rc < 1.0 < 1.0.a
+	if restA == "" && !isAlpha(restB[0]) || restA != "" && isAlpha(restA[0]) {
+		return -1
+	}
+	return 1
+}
+
+type snapRevision struct {
+	version  string
+	revision string
+}
+
+// snapRevisions reads the disabled revisions of 'snap list --all', e.g.
+// "hugo  0.135.0  20987  latest/stable  hugo-authors  disabled".
+func snapRevisions(pkg string) []snapRevision {
+	var revisions []snapRevision
+	for line := range strings.SplitSeq(commandOutput("snap list --all x", pkg), "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 6 && f[0] == pkg && strings.Contains(f[len(f)-1], "disabled") {
+			revisions = append(revisions, snapRevision{version: f[1], revision: f[2]})
+		}
+	}
+	return revisions
+}
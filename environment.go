@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// environment describes where 'i' runs, beyond the operating system.
+type environment struct {
+	Termux    bool
+	WSL       bool
+	Container string // docker, podman, lxc, kubernetes, ... or "" outside of containers
+	Systemd   bool   // systemd runs as init, snapd needs it
+}
+
+var runningEnv environment
+
+func detectEnvironment() environment {
+	e := environment{
+		Termux:    os.Getenv("TERMUX_VERSION") != "" || strings.Contains(os.Getenv("PREFIX"), "com.termux"),
+		Container: detectContainer(),
+	}
+
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		e.WSL = true
+	} else if data, err := os.ReadFile("/proc/version"); err == nil {
+		e.WSL = strings.Contains(strings.ToLower(string(data)), "microsoft")
+	}
+
+	if _, err := os.Stat("/run/systemd/system"); err == nil {
+		e.Systemd = true
+	}
+	return e
+}
+
+func detectContainer() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+	// set by systemd-nspawn, podman, lxc and others
+	if c := os.Getenv("container"); c != "" {
+		return c
+	}
+	if data, err := os.ReadFile("/proc/1/cgroup"); err == nil {
+		cgroup := string(data)
+		for _, kind := range []string{"kubepods", "docker", "containerd", "lxc"} {
+			if strings.Contains(cgroup, kind) {
+				if kind == "kubepods" {
+					return "kubernetes"
+				}
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+func (e environment) String() string {
+	var parts []string
+	if e.Termux {
+		parts = append(parts, "Termux (Android)")
+	}
+	if e.WSL {
+		parts = append(parts, "WSL")
+	}
+	if e.Container != "" {
+		parts = append(parts, fmt.Sprintf("container (%s)", e.Container))
+	}
+	if len(parts) == 0 {
+		return "regular system"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// removeUnusablePMs drops package managers that are installed but can not
+// work in this environment: snap without systemd (containers, WSL without
+// systemd) and, on WSL, Windows programs found through the Windows PATH
+// (e.g. the npm of Node.js for Windows), they would manage Windows.
+func removeUnusablePMs(pms []packageManager, e environment) []packageManager {
+	return slices.DeleteFunc(pms, func(p packageManager) bool {
+		if p.Name == "snap" && runtime.GOOS == "linux" && !e.Systemd {
+			return true
+		}
+		return e.WSL && isWindowsDrivePath(p.Path)
+	})
+}
+
+// isWindowsDrivePath reports whether path is on a Windows drive mounted by WSL, e.g. /mnt/c/.
+func isWindowsDrivePath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/mnt/")
+	return ok && len(rest) >= 2 && rest[1] == '/' && (rest[0] >= 'a' && rest[0] <= 'z')
+}
+
+// printDoctor reports what 'i' found about this system.
+func printDoctor() {
+	fmt.Printf("i the installer v%v\n\n", version)
+	fmt.Printf("operating system:     %s/%s\n", runtime.GOOS, runtime.GOARCH)
+
+	if rel, err := readOSRelease(); err == nil {
+		name := rel.Fields["PRETTY_NAME"]
+		if name == "" {
+			name = rel.Fields["NAME"]
+		}
+		fmt.Printf("distribution:         %s (ID=%s", name, rel.ID)
+		if len(rel.IDLike) > 0 {
+			fmt.Printf(", ID_LIKE=%s", strings.Join(rel.IDLike, " "))
+		}
+		if rel.VariantID != "" {
+			fmt.Printf(", VARIANT_ID=%s", rel.VariantID)
+		}
+		fmt.Println(")")
+	}
+
+	fmt.Printf("environment:          %s\n", runningEnv)
+	fmt.Printf("immutable system:     %v\n", immutableSystem)
+
+	if e, err := resolveEscalator(); err != nil {
+		fmt.Printf("privilege escalation: none available (%v)\n", err)
+	} else if e.Name == "" {
+		fmt.Println("privilege escalation: not needed")
+	} else {
+		fmt.Printf("privilege escalation: %s (%s)\n", e.Name, e.Path)
+	}
+
+	fmt.Print("package managers:    ")
+	if len(detectedPMs) == 0 {
+		fmt.Print(" none found")
+	}
+	for _, p := range detectedPMs {
+		fmt.Printf(" %s", p.Name)
+		if p.Name == pm.Name {
+			fmt.Print(" (primary)")
+		}
+	}
+	fmt.Println()
+
+	if dir, err := configDir(); err == nil {
+		path := filepath.Join(dir, "config.json")
+		if _, err := os.Stat(path); err == nil {
+			fmt.Printf("config file:          %s\n", path)
+		} else {
+			fmt.Printf("config file:          %s (not found)\n", path)
+		}
+	}
+	if dir, err := cacheDir(); err == nil {
+		fmt.Printf("cache directory:      %s\n", dir)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// installWithFallback installs pkg with the package manager chosen by the rules
+// or the primary one, or, when its Available check says the package is not there,
+// with the next detected package manager (in priority order) that has it. A failed
+// install exits. The one used is recorded for uninstall and upgrade.
+// A version ("" for the latest) is pinned in the syntax of each package manager,
+// the ones that can not pin versions are skipped.
+func installWithFallback(pkg, version string, r route) {
+	candidates := r.candidates()
+	if len(candidates) == 0 {
+		fmt.Printf("Every detected package manager is avoided by the rules for '%s'.\n", pkg)
+		os.Exit(1)
+	}
+
+	for i, p := range candidates {
+		c, ok := pm_commands[p.Name]
+		if !ok || c.Install == "" {
+			continue
+		}
+
+		name, err := pinnedName(c, r.nameFor(pkg, p.Name), version)
+		if err != nil {
+			fmt.Printf("[info] %v\n", err)
+			continue
+		}
+
+		if available, query := availableCheck(p.Name, name, r.nameFor(pkg, p.Name)); available != "" {
+			if !commandSucceeds(available, query) {
+				if !quiet {
+					fmt.Printf("[info] '%s' is not available in %s\n", name, p.Name)
+				}
+				continue
+			}
+		} else if i > 0 {
+			// no way to ask this package manager, do not try to install blindly
+			continue
+		}
+
+		if i > 0 && !quiet {
+			fmt.Printf("[info] trying %s instead\n", p.Name)
+		}
+
+		// only a missing package falls back, a failed install (lock, network, cancelled sudo) stops
+		if err := runCommand(c.Install, name); err != nil {
+			fmt.Printf("[error] installing '%s' with %s failed: %v\n", name, p.Name, err)
+			os.Exit(1)
+		}
+
+		// URLs are not names to uninstall with, built-in managers record the name themselves
+		if !isPackageURL(pkg) && !c.Builtin {
+			recordInstall(pkg, p.Name)
+		}
+		if i > 0 {
+			fmt.Printf("[info] '%s' was installed with %s\n", pkg, p.Name)
+		}
+		warnAfterChange(c)
+		return
+	}
+
+	if version != "" {
+		fmt.Printf("Version %s of '%s' could not be installed with any package manager.\n", version, pkg)
+		os.Exit(1)
+	}
+	fmt.Printf("Package '%s' could not be installed with any package manager.\n", pkg)
+	os.Exit(1)
+}
+
+// groupAvailable asks package managers with package groups (@name) whether a group exists
+var groupAvailable = map[string]string{
+	"dnf": "dnf group info x",
+	"yum": "yum group info x",
+}
+
+// availableCheck returns the command template and the name to ask pmName
+// whether name can be installed, plain is the name without a version.
+// The template is "" if it can not be asked.
+func availableCheck(pmName, name, plain string) (string, string) {
+	if group, ok := strings.CutPrefix(name, "@"); ok {
+		return groupAvailable[pmName], group
+	}
+	// a version given as a flag (e.g. snap --channel) can not be asked for
+	if strings.Contains(name, " ") {
+		return pm_commands[pmName].Available, plain
+	}
+	return pm_commands[pmName].Available, name
+}
+
+// managerFor returns the package manager to uninstall or upgrade pkg with:
+// the one that installed it through 'i' if it is still detected, else the primary one.
+func managerFor(pkg string) (string, commands) {
+	if forcedPM == "" {
+		if name := installedWith(pkg); name != "" && name != pm.Name {
+			if slices.ContainsFunc(detectedPMs, func(p packageManager) bool { return p.Name == name }) {
+				if !quiet {
+					fmt.Printf("[info] '%s' was installed with %s, using it\n", pkg, name)
+				}
+				return name, pm_commands[name]
+			}
+		}
+	}
+	return pm.Name, pm_commands[pm.Name]
+}
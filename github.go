@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// githubInstall is a tool installed from the release assets of a GitHub repository.
+type githubInstall struct {
+	Version  string   `json:"version"` // the release tag
+	Asset    string   `json:"asset"`
+	Binaries []string `json:"binaries"` // installed files
+}
+
+// githubRegistryFile records the tools installed with the github manager, by owner/repo
+const githubRegistryFile = "github.json"
+
+func readGithubRegistry() map[string]githubInstall {
+	registry := map[string]githubInstall{}
+	readState(githubRegistryFile, &registry)
+	return registry
+}
+
+// runGithub runs the github manager commands: install, uninstall, upgrade
+// (one or all), info and list, repo is "" for upgrade all and list.
+func runGithub(action, repo string) error {
+	switch action {
+	case "install":
+		return githubInstallRepo(repo, "")
+	case "uninstall":
+		return githubUninstall(repo)
+	case "upgrade":
+		if repo != "" {
+			return githubUpgrade(repo)
+		}
+		var failed []string
+		for _, r := range slices.Sorted(maps.Keys(readGithubRegistry())) {
+			if err := githubUpgrade(r); err != nil {
+				fmt.Printf("[error] upgrading %s: %v\n", r, err)
+				failed = append(failed, r)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("could not upgrade %s", strings.Join(failed, ", "))
+		}
+		return nil
+	case "info":
+		return githubInfo(repo)
+	case "list":
+		registry := readGithubRegistry()
+		for _, r := range slices.Sorted(maps.Keys(registry)) {
+			fmt.Printf("%s %s (%s)\n", r, registry[r].Version, strings.Join(registry[r].Binaries, ", "))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown github command: %s", action)
+}
+
+func latestRelease(repo string) (Release, error) {
+	return getRelease(fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo))
+}
+
+// githubInstallRepo installs the binaries of the latest release of repo into
+// the user bin directory, replacing the ones of an older version.
+func githubInstallRepo(repo, installed string) error {
+	release, err := latestRelease(repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo, err)
+	}
+	if release.TagName == installed {
+		fmt.Printf("%s is up to date (%s)\n", repo, installed)
+		return nil
+	}
+
+	var assetNames []string
+	for _, a := range release.Assets {
+		assetNames = append(assetNames, a.Name)
+	}
+	asset, ok := pickReleaseAsset(assetNames, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return fmt.Errorf("the %s release of %s has no asset for %s/%s", release.TagName, repo, runtime.GOOS, runtime.GOARCH)
+	}
+	url := release.Assets[slices.Index(assetNames, asset)].DownloadURL
+
+	tmpDir, err := os.MkdirTemp("", "i-github-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if !quiet {
+		fmt.Printf("[info] downloading %s (%s)\n", asset, release.TagName)
+	}
+	archive := filepath.Join(tmpDir, asset)
+	f, err := os.Create(archive)
+	if err != nil {
+		return err
+	}
+	err = downloadFile(url, f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("download failed: %w", err)
+	}
+
+	_, name := filepath.Split(repo)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	extracted := filepath.Join(tmpDir, "extracted")
+	if err := extractArchive(archive, name, extracted); err != nil {
+		return fmt.Errorf("extracting %s: %w", asset, err)
+	}
+	binaries, err := findExecutables(extracted)
+	if err != nil {
+		return err
+	}
+	if len(binaries) == 0 {
+		return fmt.Errorf("no executables found in %s", asset)
+	}
+
+	binDir, err := userBinDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return err
+	}
+	var installedFiles []string
+	for _, bin := range binaries {
+		dest := filepath.Join(binDir, filepath.Base(bin))
+		// remove first, a running binary can not be overwritten on some systems
+		os.Remove(dest)
+		if err := copyFile(bin, dest); err != nil {
+			return err
+		}
+		if err := os.Chmod(dest, 0755); err != nil {
+			return err
+		}
+		installedFiles = append(installedFiles, dest)
+		if !quiet {
+			fmt.Printf("[info] installed %s\n", dest)
+		}
+	}
+
+	registry := readGithubRegistry()
+	// binaries of the old version that are not in the new one
+	for _, old := range registry[repo].Binaries {
+		if !slices.Contains(installedFiles, old) {
+			os.Remove(old)
+		}
+	}
+	registry[repo] = githubInstall{Version: release.TagName, Asset: asset, Binaries: installedFiles}
+	if err := writeState(githubRegistryFile, registry); err != nil {
+		return err
+	}
+	warnIfNotInPath(binDir)
+	return nil
+}
+
+func githubUpgrade(repo string) error {
+	installed, ok := readGithubRegistry()[repo]
+	if !ok {
+		return fmt.Errorf("%s is not installed with github", repo)
+	}
+	return githubInstallRepo(repo, installed.Version)
+}
+
+func githubUninstall(repo string) error {
+	registry := readGithubRegistry()
+	installed, ok := registry[repo]
+	if !ok {
+		return fmt.Errorf("%s is not installed with github", repo)
+	}
+	for _, bin := range installed.Binaries {
+		if err := os.Remove(bin); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if !quiet {
+			fmt.Printf("[info] removed %s\n", bin)
+		}
+	}
+	delete(registry, repo)
+	return writeState(githubRegistryFile, registry)
+}
+
+func githubInfo(repo string) error {
+	release, err := latestRelease(repo)
+	if err != nil {
+		return fmt.Errorf("%s: %w", repo, err)
+	}
+	fmt.Printf("repository: https://github.com/%s\n", repo)
+	fmt.Printf("latest:     %s (%s)\n", release.TagName, release.PublishedAt.Format("2006-01-02"))
+	if installed, ok := readGithubRegistry()[repo]; ok {
+		fmt.Printf("installed:  %s (%s)\n", installed.Version, strings.Join(installed.Binaries, ", "))
+	} else {
+		fmt.Println("installed:  no")
+	}
+	return nil
+}
+
+// osTokens are the words release assets use for each operating system
+var osTokens = map[string][]string{
+	"linux":   {"linux"},
+	"darwin":  {"darwin", "macos", "apple", "osx", "mac"},
+	"windows": {"windows", "win", "win64", "win32"},
+	"freebsd": {"freebsd"},
+}
+
+// assetArch returns the GOARCH an asset name is built for, "universal" for
+// macOS universal binaries or "" if the name does not tell.
+func assetArch(lower string, words []string) string {
+	has := func(tokens ...string) bool {
+		return slices.ContainsFunc(tokens, func(t string) bool { return strings.Contains(lower, t) })
+	}
+	// the order matters: arm is in arm64
+	switch {
+	case has("aarch64", "arm64"):
+		return "arm64"
+	case has("x86_64", "amd64", "x64"):
+		return "amd64"
+	case has("i386", "i686") || slices.Contains(words, "386"):
+		return "386"
+	case has("armv7", "armhf", "armv6") || slices.Contains(words, "arm"):
+		return "arm"
+	case has("universal"):
+		return "universal"
+	}
+	return ""
+}
+
+// skippedAssets are files next to the binaries in releases: checksums,
+// signatures, metadata and packages for other installers
+var skippedAssets = []string{
+	".sha256", ".sha512", ".sha256sum", ".md5", ".sig", ".asc", ".pem", ".sbom", ".json", ".txt",
+	".deb", ".rpm", ".apk", ".msi", ".pkg", ".dmg", ".appimage", ".flatpak", ".snap", ".vsix",
+}
+
+// pickReleaseAsset chooses the release asset for goos/goarch: its name must
+// name the operating system and the architecture (macOS universal binaries
+// fit every architecture). Static musl builds and tar archives are preferred.
+func pickReleaseAsset(names []string, goos, goarch string) (string, bool) {
+	best, bestScore := "", -1
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		if slices.ContainsFunc(skippedAssets, func(ext string) bool { return strings.HasSuffix(lower, ext) }) {
+			continue
+		}
+		words := strings.FieldsFunc(lower, func(r rune) bool { return r == '-' || r == '_' || r == '.' || r == ' ' })
+		if !slices.ContainsFunc(osTokens[goos], func(t string) bool { return slices.Contains(words, t) }) {
+			continue
+		}
+
+		arch := assetArch(lower, words)
+		if arch != goarch && !(arch == "universal" && goos == "darwin") {
+			continue
+		}
+
+		score := 0
+		if goos == "linux" && strings.Contains(lower, "musl") {
+			score += 2
+		}
+		if strings.Contains(lower, ".tar.") || strings.HasSuffix(lower, ".tgz") {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// immutableSystem is set when the root file system is read-only and packages
+// are layered into a new deployment (ostree, transactional-update) or declared (NixOS).
+var immutableSystem bool
+
+// ostreeVariants are the VARIANT_IDs of Fedora Atomic desktops and CoreOS
+var ostreeVariants = []string{"silverblue", "kinoite", "sericea", "onyx", "cosmic-atomic", "atomic", "iot", "coreos"}
+
+// ostreeBootedPath exists on systems booted from an ostree deployment
+var ostreeBootedPath = "/run/ostree-booted"
+
+// transactionalIDs are the os-release IDs of the openSUSE/SUSE transactional systems
+var transactionalIDs = []string{"opensuse-microos", "opensuse-aeon", "opensuse-kalpa", "sl-micro", "suse-microos"}
+
+// mutablePMs are the package managers that must not be used on each immutable system,
+// their binaries may exist but they fail or bypass the layering tool there.
+var mutablePMs = map[string][]string{
+	"rpm-ostree":           {"dnf", "yum", "rpm"},
+	"transactional-update": {"zypper", "rpm"},
+	"flatpak":              {"apt"}, // Endless OS
+}
+
+// immutableSystemPM returns the package manager to use on immutable and
+// atomic distributions, or "" for regular ones.
+func immutableSystemPM(rel osRelease) string {
+	switch {
+	case rel.ID == "endless":
+		return "flatpak" // ostree-based too, but apps only come from flatpak
+	case rel.ID == "nixos":
+		return "nix-env"
+	case slices.Contains(transactionalIDs, rel.ID) || rel.VariantID == "microos":
+		return "transactional-update"
+	case slices.Contains(ostreeVariants, rel.VariantID):
+		return "rpm-ostree"
+	}
+	if _, err := os.Stat(ostreeBootedPath); err == nil {
+		if ok, _ := isInstalled("rpm-ostree"); ok {
+			return "rpm-ostree"
+		}
+	}
+	return ""
+}
+
+// removeMutablePMs drops the package managers that do not work on the detected immutable system.
+func removeMutablePMs(pms []packageManager, systemPM string) []packageManager {
+	excluded := mutablePMs[systemPM]
+	return slices.DeleteFunc(pms, func(p packageManager) bool {
+		return slices.Contains(excluded, p.Name)
+	})
+}
+
+// preferFlatpak reports whether pkg should be installed with flatpak instead of
+// being layered into the system image, and returns its flatpak application ID.
+// On immutable systems GUI apps belong in flatpak, which needs no reboot,
+// command line tools are layered.
+func preferFlatpak(pkg string) (string, bool) {
+	if !immutableSystem || forcedPM != "" || pm.Name == "flatpak" {
+		return "", false
+	}
+	if !slices.ContainsFunc(detectedPMs, func(p packageManager) bool { return p.Name == "flatpak" }) {
+		return "", false
+	}
+	return findGUIFlatpakApp(pkg)
+}
+
+// flatpakOutput runs flatpak and returns its output, the tests replace it.
+var flatpakOutput = func(args ...string) ([]byte, error) {
+	return exec.Command("flatpak", args...).Output()
+}
+
+// findGUIFlatpakApp returns the flatpak application ID of pkg if it is a desktop app.
+func findGUIFlatpakApp(pkg string) (string, bool) {
+	appID, ok := findFlatpakApp(pkg)
+	if !ok || !isGUIFlatpak(appID) {
+		return "", false
+	}
+	return appID, true
+}
+
+// isGUIFlatpak reports whether the flatpak app uses a display, by the metadata of the first remote that has it.
+func isGUIFlatpak(appID string) bool {
+	out, err := flatpakOutput("remotes", "--columns=name")
+	if err != nil {
+		return false
+	}
+	for _, remote := range strings.Fields(string(out)) {
+		metadata, err := flatpakOutput("remote-info", "--show-metadata", remote, appID)
+		if err == nil {
+			return usesDisplay(string(metadata))
+		}
+	}
+	return false
+}
+
+// usesDisplay reports whether flatpak metadata gives the app access to X11 or Wayland.
+func usesDisplay(metadata string) bool {
+	for line := range strings.SplitSeq(metadata, "\n") {
+		sockets, ok := strings.CutPrefix(strings.TrimSpace(line), "sockets=")
+		if !ok {
+			continue
+		}
+		for socket := range strings.SplitSeq(sockets, ";") {
+			if socket == "x11" || socket == "wayland" || socket == "fallback-x11" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findFlatpakApp searches the flatpak remotes for an application named pkg.
+func findFlatpakApp(pkg string) (string, bool) {
+	out, err := flatpakOutput("search", "--columns=application,name", pkg)
+	if err != nil {
+		return "", false
+	}
+	return matchFlatpakApp(string(out), pkg)
+}
+
+// matchFlatpakApp returns the application ID of the first line of
+// "application<TAB>name" columns whose ID or name is pkg.
+func matchFlatpakApp(out, pkg string) (string, bool) {
+	for line := range strings.SplitSeq(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		appID, name := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if strings.EqualFold(appID, pkg) || strings.EqualFold(name, pkg) || strings.HasSuffix(strings.ToLower(appID), "."+strings.ToLower(pkg)) {
+			return appID, true
+		}
+	}
+	return "", false
+}
+
+// installedName is the name to uninstall or upgrade pkg with pmName: the
+// translated name, for flatpak the ID of the installed app named pkg.
+func installedName(pkg, pmName string) string {
+	name := translateName(pkg, pmName)
+	if pmName != "flatpak" || strings.Contains(name, ".") {
+		return name
+	}
+	out, err := flatpakOutput("list", "--app", "--columns=application,name")
+	if err != nil {
+		return name
+	}
+	if appID, ok := matchFlatpakApp(string(out), name); ok {
+		return appID
+	}
+	return name
+}
+
+// warnAfterChange tells what else is needed after changing packages on an immutable system.
+func warnAfterChange(c commands) {
+	if c.NeedsReboot {
+		fmt.Println("[warn] the change is applied to a new deployment, reboot to use it (e.g. 'systemctl reboot')")
+	}
+	if immutableSystem && c.Name == "nix-env" {
+		fmt.Println("[warn] NixOS is configured declaratively, add the package to configuration.nix to keep it after a rebuild")
+	}
+}
@@ -2,11 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -20,110 +20,263 @@ const (
 )
 
 type Release struct {
-	Assets []struct {
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
 		Name        string `json:"name"`
 		DownloadURL string `json:"browser_download_url"`
 	} `json:"assets"`
 }
 
 func installLatestVersion() {
+	if err := upgradeSelf(); err != nil {
+		fail("%v", err)
+	}
+}
+
+// upgradeSelf does the actual work of installLatestVersion, errors are
+// returned instead of exiting so the deferred cleanup of the temp file runs.
+func upgradeSelf() error {
 	assetName, err := detectAsset()
 	if err != nil {
-		fail("Detection failed: %v", err)
+		return fmt.Errorf("Detection failed: %w", err)
 	}
 	fmt.Printf("Detected system: %s/%s. Looking for asset: %s\n", runtime.GOOS, runtime.GOARCH, assetName)
 
 	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", GithubRepo)
-	downloadURL, newVersion, err := getDownloadURL(apiURL, assetName)
+	release, err := getRelease(apiURL)
 	if err != nil {
-		fail("Failed to find download URL: %v", err)
+		return fmt.Errorf("Failed to find download URL: %w", err)
+	}
+	downloadURL, newVersion, err := release.findAsset(assetName)
+	if err != nil {
+		return fmt.Errorf("Failed to find download URL: %w", err)
 	}
 
 	if strings.Contains(newVersion, version) {
-		fmt.Printf("[info] your version of 'i' is up to date.\nInstalled version: v%v\nUpstream version: %v", version, newVersion)
-		return
+		fmt.Printf("[info] your version of 'i' is up to date.\nInstalled version: v%v\nUpstream version: %v\n", version, newVersion)
+		return nil
+	}
+
+	printReleaseNotes(release, newVersion)
+	if !askConfirmation(fmt.Sprintf("Upgrade i %v to %v?", version, newVersion)) {
+		fmt.Println("Aborted.")
+		return nil
 	}
 
 	fmt.Printf("[info] upgrade i %v to %v\n[info] downloading: %s\n", version, newVersion, downloadURL)
 
 	tmpFile, err := os.CreateTemp("", "i-installer-*")
 	if err != nil {
-		fail("Failed to create temp file: %v", err)
+		return fmt.Errorf("Failed to create temp file: %w", err)
 	}
 	defer os.Remove(tmpFile.Name()) // clean up on exit
 	defer tmpFile.Close()
 
 	if err := downloadFile(downloadURL, tmpFile); err != nil {
-		fail("Download failed: %v", err)
+		return fmt.Errorf("Download failed: %w", err)
 	}
 	tmpFile.Close() // Close 'explicitly' before moving/copying
 
 	installDir := os.Getenv("INSTALL_DIR")
-	if installDir == "" {
+	if userMode {
+		if installDir, err = userBinDir(); err != nil {
+			return fmt.Errorf("Failed to find the user bin directory: %w", err)
+		}
+	} else if installDir == "" {
 		installDir = DefaultDir
 	}
-	targetPath := filepath.Join(installDir, InstallName)
+	targetPath := filepath.Join(installDir, installName())
 
 	fmt.Printf("Installing to %s...\n", targetPath)
-	if err := installBinary(tmpFile.Name(), targetPath); err != nil {
-		fail("Installation failed: %v", err)
+	err = installBinary(tmpFile.Name(), targetPath)
+	if errors.Is(err, errNoSuperUser) {
+		// same as installing without root: fall back to the per-user bin directory
+		userDir, dirErr := userBinDir()
+		if dirErr != nil {
+			return fmt.Errorf("Installation failed: %w", err)
+		}
+		targetPath = filepath.Join(userDir, installName())
+		fmt.Printf("[info] no permission to write to %s, installing to %s instead\n", installDir, targetPath)
+		err = installBinary(tmpFile.Name(), targetPath)
+	}
+	if err != nil {
+		return fmt.Errorf("Installation failed: %w", err)
+	}
+
+	fmt.Printf("[info] successfully installed '%s' to '%s'\n", installName(), targetPath)
+	if userMode {
+		offerToAddToPath(filepath.Dir(targetPath))
+	} else {
+		warnIfNotInPath(filepath.Dir(targetPath))
 	}
+	return nil
+}
 
-	fmt.Printf("[info] successfully installed '%s' to '%s'\n", InstallName, targetPath)
+// installName is the file name of the installed executable, INSTALL_NAME overrides it.
+func installName() string {
+	if name := os.Getenv("INSTALL_NAME"); name != "" {
+		return name
+	}
+	if runtime.GOOS == "windows" {
+		return InstallName + ".exe"
+	}
+	return InstallName
 }
 
-func detectAsset() (string, error) {
-	switch runtime.GOOS {
-	case "linux":
-		switch runtime.GOARCH {
-		case "amd64":
-			return "i-linux-x64", nil
-		case "arm64":
-			return "i-linux-arm64", nil
+// userBinDir is the per-user executables directory, $XDG_BIN_HOME or ~/.local/bin.
+func userBinDir() (string, error) {
+	if dir := os.Getenv("XDG_BIN_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "bin"), nil
+}
+
+// inPath reports whether dir is one of the directories listed in PATH.
+func inPath(dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && filepath.Clean(p) == dir {
+			return true
 		}
-	case "darwin":
-		switch runtime.GOARCH {
-		case "amd64":
-			return "i-macos-intel-x64", nil
-		case "arm64":
-			return "i-macos-apple-silicon-arm64", nil
+	}
+	return false
+}
+
+func warnIfNotInPath(dir string) {
+	if inPath(dir) {
+		return
+	}
+	fmt.Printf("[warn] %s is not in your PATH, add it to your shell profile:\n  export PATH=\"%s:$PATH\"\n", dir, dir)
+}
+
+// offerToAddToPath asks to append dir to PATH in the rc file of the user's shell.
+func offerToAddToPath(dir string) {
+	if inPath(dir) {
+		return
+	}
+	rcFile, line := shellProfile(dir)
+	if rcFile == "" {
+		warnIfNotInPath(dir)
+		return
+	}
+
+	if data, err := os.ReadFile(rcFile); err == nil && strings.Contains(string(data), line) {
+		fmt.Printf("[info] %s already adds %s to PATH, open a new shell to use it\n", rcFile, dir)
+		return
+	}
+
+	fmt.Printf("[info] %s is not in your PATH\n", dir)
+	if !askConfirmation(fmt.Sprintf("Add it to %s?", rcFile)) {
+		warnIfNotInPath(dir)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rcFile), 0755); err != nil {
+		fmt.Printf("[error] can not create %s: %v\n", filepath.Dir(rcFile), err)
+		return
+	}
+	f, err := os.OpenFile(rcFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Printf("[error] can not open %s: %v\n", rcFile, err)
+		return
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "\n# added by 'i'\n%s\n", line); err != nil {
+		fmt.Printf("[error] can not write to %s: %v\n", rcFile, err)
+		return
+	}
+	fmt.Printf("[info] added %s to PATH in %s, open a new shell to use it\n", dir, rcFile)
+}
+
+// shellProfile returns the rc file of the user's login shell and the line that puts dir in PATH.
+func shellProfile(dir string) (string, string) {
+	if runtime.GOOS == "windows" {
+		return "", ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ""
+	}
+
+	exportLine := fmt.Sprintf("export PATH=\"%s:$PATH\"", dir)
+
+	switch filepath.Base(os.Getenv("SHELL")) {
+	case "zsh":
+		zdotdir := os.Getenv("ZDOTDIR")
+		if zdotdir == "" {
+			zdotdir = home
 		}
-	case "windows":
-		switch runtime.GOARCH {
-		case "amd64":
-			return "i-windows-x64", nil
-		case "arm64":
-			return "i-windows-arm64", nil
+		return filepath.Join(zdotdir, ".zshrc"), exportLine
+	case "bash":
+		if runtime.GOOS == "darwin" {
+			// Terminal.app starts login shells, which read .bash_profile only
+			return filepath.Join(home, ".bash_profile"), exportLine
 		}
+		return filepath.Join(home, ".bashrc"), exportLine
+	case "fish":
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(home, ".config")
+		}
+		return filepath.Join(configHome, "fish", "config.fish"), fmt.Sprintf("fish_add_path %s", dir)
+	default:
+		return filepath.Join(home, ".profile"), exportLine
 	}
-	return "", fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+func detectAsset() (string, error) {
+	return assetFor(runtime.GOOS, runtime.GOARCH)
 }
 
 func getDownloadURL(apiURL, assetTarget string) (string, string, error) {
+	release, err := getRelease(apiURL)
+	if err != nil {
+		return "", "", err
+	}
+	return release.findAsset(assetTarget)
+}
+
+// getRelease fetches and decodes a release from the GitHub releases API.
+func getRelease(apiURL string) (Release, error) {
+	var release Release
+
 	client := &http.Client{Timeout: 30 * time.Second}
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
-		return "", "", err
+		return release, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("User-Agent", "i-installer-go")
+	// authenticated requests have a higher rate limit
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", "", err
+		return release, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("API request failed with status: %s", resp.Status)
+		return release, fmt.Errorf("API request failed with status: %s", resp.Status)
 	}
 
-	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", "", err
-	}
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	return release, err
+}
 
-	for _, asset := range release.Assets {
+// findAsset returns the download URL and the version of the asset named like assetTarget.
+func (r Release) findAsset(assetTarget string) (string, string, error) {
+	for _, asset := range r.Assets {
 		if strings.Contains(asset.Name, assetTarget) {
 			newVersion := lastAfterDash(asset.Name)
 			return asset.DownloadURL, newVersion, nil
@@ -151,7 +304,7 @@ func downloadFile(url string, dest *os.File) error {
 func installBinary(srcPath, destPath string) error {
 	dir := filepath.Dir(destPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		if os.IsPermission(err) {
+		if os.IsPermission(err) && !userMode {
 			if err := runAsSuperUser("mkdir", "-p", dir); err != nil {
 				return fmt.Errorf("sudo/doas mkdir failed: %w", err)
 			}
@@ -165,7 +318,7 @@ func installBinary(srcPath, destPath string) error {
 		return os.Chmod(destPath, 0755)
 	}
 
-	if os.IsPermission(err) {
+	if os.IsPermission(err) && !userMode {
 		fmt.Println("Permission denied. Attempting installation with sudo/doas...")
 		if err := runAsSuperUser("cp", srcPath, destPath); err != nil {
 			return fmt.Errorf("sudo/doas cp failed: %w", err)
@@ -196,23 +349,6 @@ func copyFile(src, dst string) error {
 	return err
 }
 
-func runAsSuperUser(args ...string) error {
-	var cmd *exec.Cmd
-	if _, err := exec.LookPath("sudo"); err == nil {
-		fmt.Println("[info] found 'sudo', try running the command with it")
-		cmd = exec.Command("sudo", args...)
-	} else if _, err := exec.LookPath("doas"); err == nil {
-		fmt.Println("[info] found 'doas', try running the command with it")
-		cmd = exec.Command("doas", args...)
-	} else {
-		return fmt.Errorf("cannot run the command: permission denied and sudo/doas not found.\n")
-	}
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
 func fail(format string, args ...any) {
 	fmt.Fprintf(os.Stderr, "ERROR: "+format+"\n", args...)
 	os.Exit(1)
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// packageFormat is a kind of package file and the package managers that install it.
+type packageFormat struct {
+	Name       string
+	Extensions []string
+	Magic      [][]byte // the file starts with one of them, nil to trust the extension
+	PMs        []string
+	Install    string // replaces the InstallFile of the package manager, "" to use it
+}
+
+var packageFormats = []packageFormat{
+	{"deb", []string{".deb"}, [][]byte{[]byte("!<arch>\ndebian-binary")}, []string{"apt"}, ""},
+	{"rpm", []string{".rpm"}, [][]byte{{0xed, 0xab, 0xee, 0xdb}}, []string{"dnf", "yum", "zypper", "rpm-ostree", "transactional-update", "rpm"}, ""},
+	// zstd, xz or gzip compressed tar
+	{"pacman", []string{".pkg.tar.zst", ".pkg.tar.xz", ".pkg.tar.gz"}, [][]byte{{0x28, 0xb5, 0x2f, 0xfd}, {0xfd, '7', 'z', 'X', 'Z', 0}, {0x1f, 0x8b}}, []string{"pacman"}, ""},
+	// apk v2 is gzip, apk v3 is ADB, Android apps (zip) are rejected
+	{"apk", []string{".apk"}, [][]byte{{0x1f, 0x8b}, []byte("ADB")}, []string{"apk"}, ""},
+	{"flatpakref", []string{".flatpakref"}, [][]byte{[]byte("[Flatpak Ref]")}, []string{"flatpak"}, ""},
+	{"flatpak bundle", []string{".flatpak"}, nil, []string{"flatpak"}, "sudo flatpak install -y --bundle x"},
+	{"AppImage", []string{".appimage"}, [][]byte{[]byte("\x7fELF")}, []string{"appimage"}, ""},
+}
+
+// isPackageURL reports whether arg is an HTTP(S) URL to install a package from.
+func isPackageURL(arg string) bool {
+	return strings.HasPrefix(arg, "https://") || strings.HasPrefix(arg, "http://")
+}
+
+// isPackageFile reports whether arg names a package file instead of a package:
+// a URL, a name with a package extension or a path with a directory (./download)
+// to an existing file, so user/tap/formula stays a package.
+func isPackageFile(arg string) bool {
+	if isPackageURL(arg) {
+		return true
+	}
+	if _, ok := formatByExtension(arg); ok {
+		return true
+	}
+	if !strings.ContainsRune(arg, '/') && !strings.ContainsRune(arg, filepath.Separator) {
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
+
+// isPathLike reports whether arg is written as a path (./, ../ or absolute) or
+// is not a URL and has a package extension, it is a file even if it does not exist.
+func isPathLike(arg string) bool {
+	for _, prefix := range []string{"./", "../", "." + string(filepath.Separator), ".." + string(filepath.Separator)} {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	if filepath.IsAbs(arg) || strings.HasPrefix(arg, "/") {
+		return true
+	}
+	_, ok := formatByExtension(arg)
+	return ok && !isPackageURL(arg)
+}
+
+func formatByExtension(name string) (packageFormat, bool) {
+	name = strings.ToLower(name)
+	for _, f := range packageFormats {
+		for _, ext := range f.Extensions {
+			if strings.HasSuffix(name, ext) {
+				return f, true
+			}
+		}
+	}
+	return packageFormat{}, false
+}
+
+// detectFormat finds the format of a package file by its extension and checks
+// its first bytes, files without a known extension are recognized by their magic bytes.
+func detectFormat(file string) (packageFormat, error) {
+	head := make([]byte, 64)
+	fd, err := os.Open(file)
+	if err != nil {
+		return packageFormat{}, err
+	}
+	n, err := io.ReadFull(fd, head)
+	fd.Close()
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return packageFormat{}, err
+	}
+	head = head[:n]
+
+	matches := func(f packageFormat) bool {
+		return slices.ContainsFunc(f.Magic, func(m []byte) bool { return bytes.HasPrefix(head, m) })
+	}
+
+	if f, ok := formatByExtension(file); ok {
+		if f.Magic != nil && !matches(f) {
+			return f, fmt.Errorf("%s is not a valid %s package", file, f.Name)
+		}
+		return f, nil
+	}
+	// gzip is not enough to tell the format
+	for _, f := range packageFormats {
+		if f.Name != "pacman" && f.Name != "apk" && matches(f) {
+			return f, nil
+		}
+	}
+	return packageFormat{}, fmt.Errorf("%s is not a known package format (.deb, .rpm, .apk, .pkg.tar.zst, .flatpakref, .flatpak, .AppImage)", file)
+}
+
+// installPackageFile installs a local package file or downloads it from a URL
+// first, with the detected package manager that handles its format.
+func installPackageFile(arg string) error {
+	file := arg
+	if isPackageURL(arg) {
+		downloaded, err := downloadPackage(arg)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(downloaded)
+		file = downloaded
+	}
+
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsFunc(file, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' }) {
+		return fmt.Errorf("paths with spaces are not supported: %s", file)
+	}
+	if info, err := os.Stat(file); err != nil {
+		return err
+	} else if info.IsDir() {
+		return fmt.Errorf("%s is a directory", file)
+	}
+
+	f, err := detectFormat(file)
+	if err != nil {
+		return err
+	}
+
+	// built-in package managers are always available on their operating systems
+	candidates := slices.Clone(detectedPMs)
+	for _, name := range f.PMs {
+		if c := pm_commands[name]; c.Builtin && slices.Contains(c.OS, runtime.GOOS) {
+			candidates = append(candidates, packageManager{Name: name})
+		}
+	}
+	i := slices.IndexFunc(candidates, func(p packageManager) bool {
+		return slices.Contains(f.PMs, p.Name) && pm_commands[p.Name].InstallFile != ""
+	})
+	if i < 0 {
+		return fmt.Errorf("%s packages need %s, which is not available on this system", f.Name, strings.Join(f.PMs, " or "))
+	}
+	c := pm_commands[candidates[i].Name]
+
+	if err := checkPackageArch(f, file); err != nil {
+		return err
+	}
+
+	if !quiet {
+		fmt.Printf("[info] installing the %s package %s with %s\n", f.Name, filepath.Base(file), c.Name)
+	}
+	install := c.InstallFile
+	if f.Install != "" {
+		install = f.Install
+	}
+	if err := runCommand(install, file); err != nil {
+		return err
+	}
+	warnAfterChange(c)
+	return nil
+}
+
+// downloadPackage downloads a package to a temp file, keeping the extension
+// of the URL so the package manager recognizes it.
+func downloadPackage(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	ext := ""
+	if f, ok := formatByExtension(path.Base(u.Path)); ok {
+		for _, e := range f.Extensions {
+			if strings.HasSuffix(strings.ToLower(u.Path), e) {
+				ext = e
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "i-package-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	if !quiet {
+		fmt.Printf("[info] downloading %s\n", rawURL)
+	}
+	err = downloadFile(rawURL, tmp)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	if ext != "" {
+		return tmp.Name(), nil
+	}
+
+	// no extension in the URL, add the one of the detected format
+	f, err := detectFormat(tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("%s: %w", rawURL, err)
+	}
+	name := tmp.Name() + f.Extensions[0]
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return name, nil
+}
+
+// checkPackageArch compares the architecture of .deb and .rpm packages with
+// the one of the system, when the tools to read them are installed.
+func checkPackageArch(f packageFormat, file string) error {
+	var pkgArch, hostArch string
+	switch f.Name {
+	case "deb":
+		if ok, _ := isInstalled("dpkg-deb"); !ok {
+			return nil
+		}
+		out, _ := exec.Command("dpkg-deb", "-f", file, "Architecture").Output()
+		pkgArch = strings.TrimSpace(string(out))
+		hostArch = commandOutput("dpkg --print-architecture", "")
+	case "rpm":
+		if ok, _ := isInstalled("rpm"); !ok {
+			return nil
+		}
+		pkgArch = commandOutput("rpm -qp --qf %{ARCH} x", file)
+		hostArch = commandOutput("uname -m", "")
+	default:
+		return nil
+	}
+	if pkgArch == "" || hostArch == "" || pkgArch == hostArch || pkgArch == "all" || pkgArch == "noarch" {
+		return nil
+	}
+	return fmt.Errorf("the package is built for %s, this system is %s", pkgArch, hostArch)
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,6 +26,8 @@ var (
 	quiet           bool = false
 	forcedPM        string
 	forcesh         bool = false
+	userMode        bool = false // install/uninstall 'i' itself per user, without root
+	assumeYes       bool = false // answer yes to the confirmations, but not to running downloaded scripts
 )
 
 type packageManager struct {
@@ -28,6 +37,7 @@ type packageManager struct {
 
 var pm packageManager
 var detectedPMs []packageManager
+var systemPMName string // the package manager of the OS/distribution, if detected
 
 func main() {
 	if len(os.Args) < 2 {
@@ -39,6 +49,7 @@ func main() {
 	args := os.Args[1:]
 	var action string
 	var pkgName string
+	var targetVersion string // the optional version of 'i downgrade pkg version'
 
 	// Simple custom parsing to handle flags mixed with args
 	for i := range args {
@@ -55,6 +66,10 @@ func main() {
 				return
 			case "--forcesh", "-sh":
 				forcesh = true
+			case "--user":
+				userMode = true
+			case "--yes", "-y":
+				assumeYes = true
 			default:
 				// Check for specific PM flags (e.g., --apt, --brew)
 				if after, ok := strings.CutPrefix(arg, "--"); ok {
@@ -73,6 +88,8 @@ func main() {
 				action = arg
 			} else if pkgName == "" {
 				pkgName = arg
+			} else if action == "downgrade" && targetVersion == "" {
+				targetVersion = arg
 			} else {
 				// Multiple packages or extra args?
 				// For now, let's just append to pkgName or warn.
@@ -90,8 +107,60 @@ func main() {
 		return
 	}
 
-	// Detect OS and PM
+	loadConfig()
+	loadNames()
+
+	if action == updateCheckAction {
+		runUpdateCheck()
+		return
+	}
+
+	if updateCheckEnabled(action) {
+		scheduleUpdateCheck()
+		defer printUpdateNotice()
+	}
+
+	// listing the supported package managers needs no detection
+	if action == "pmlist" {
+		var pms []string
+		for k := range pm_commands {
+			if k == "i" {
+				continue
+			}
+			pms = append(pms, k)
+		}
+		sort.Strings(pms)
+		fmt.Println("Supported package managers:")
+		for _, pm := range pms {
+			fmt.Println("- " + pm)
+		}
+		return
+	}
+
+	// names only one package manager takes (e.g. owner/repo for github, AppImage URLs) select it
+	// for the actions on packages, paths are files to install and rules win
+	switch action {
+	case "install", "add", "update", "upgrade", "up", "uninstall", "remove", "rm", "un":
+		if forcedPM != "" || pkgName == "" || isPathLike(pkgName) {
+			break
+		}
+		if _, err := os.Stat(pkgName); err == nil {
+			break
+		}
+		if name := pmForName(pkgName); name != "" && !ruleOverrides(pkgName, name) {
+			forcedPM = name
+		}
+	}
+
+	// Detect OS, environment and PM
+	runningEnv = detectEnvironment()
 	detectPM()
+	addBuiltinPMs()
+
+	if action == "doctor" {
+		printDoctor()
+		return
+	}
 
 	if pm.Name == "" {
 		fmt.Println("No supported package manager found.")
@@ -102,12 +171,20 @@ func main() {
 		fmt.Printf("[info] using package manager: %s\n", pm.Name)
 	}
 
-	if pkgName != "" {
-		if !validateInput(pkgName) {
+	// package files and URLs are checked by installPackageFile
+	installingFile := (action == "install" || action == "add") && isPackageFile(pkgName) && !matchesNamePattern(pm.Name, pkgName)
+	if pkgName != "" && !installingFile {
+		// versions may have more characters than names, e.g. the epoch in curl=1:7.88
+		name, version := splitVersion(pkgName)
+		if !validateInput(pkgName) && !matchesNamePattern(pm.Name, pkgName) && (!validateInput(name) || !validVersion.MatchString(version)) {
 			fmt.Printf("Invalid package name: %s\n", pkgName)
 			os.Exit(1)
 		}
 	}
+	if targetVersion != "" && !validVersion.MatchString(targetVersion) {
+		fmt.Printf("Invalid version: %s\n", targetVersion)
+		os.Exit(1)
+	}
 
 	cmds, ok := pm_commands[pm.Name]
 	if !ok {
@@ -122,7 +199,25 @@ func main() {
 		"search": true, "find": true,
 	}
 
-	if cmds.UpdateIndex != "" && updateRequiredActions[action] {
+	// upgrading everything runs many super user commands, ask for the password once up front
+	if (action == "update" || action == "upgrade" || action == "up") && pkgName == "" {
+		var batch []string
+		for _, p := range detectedPMs {
+			if c, ok := pm_commands[p.Name]; ok {
+				batch = append(batch, c.UpdateIndex, c.UpgradeAll)
+			}
+		}
+		if needsSuperUser(batch...) {
+			stop, err := preauthenticate()
+			if err != nil {
+				fmt.Println("[error] can not upgrade all packages:", err)
+				os.Exit(1)
+			}
+			defer stop()
+		}
+	}
+
+	if cmds.UpdateIndex != "" && updateRequiredActions[action] && !installingFile {
 		if !quiet {
 			fmt.Println("[info] updating local index...")
 		}
@@ -130,24 +225,23 @@ func main() {
 	}
 
 	switch action {
-	case "pmlist":
-		var pms []string
-		for k := range pm_commands {
-			if k == "i" {
-				continue
-			}
-			pms = append(pms, k)
-		}
-		sort.Strings(pms)
-		fmt.Println("Supported package managers:")
-		for _, pm := range pms {
-			fmt.Println("- " + pm)
-		}
-		return
 	case "pms":
-		fmt.Println("Available package managers:")
+		fmt.Println("Available package managers (lower priority is preferred):")
 		for _, p := range detectedPMs {
-			fmt.Println("- " + p.Name)
+			line := fmt.Sprintf("- %s (priority %d", p.Name, pmPriority(p.Name))
+			if _, ok := cfg.Priorities[p.Name]; ok {
+				line += ", set in config"
+			}
+			if p.Name == systemPMName {
+				line += ", system package manager"
+			}
+			if pm_commands[p.Name].UserLevel {
+				line += ", user-level"
+			}
+			if p.Name == pm.Name {
+				line += ", primary"
+			}
+			fmt.Println(line + ")")
 		}
 		return
 	case "info", "show":
@@ -155,14 +249,14 @@ func main() {
 			fmt.Println("No package specified.")
 			return
 		}
-		executeCommand(cmds.Info, pkgName)
+		executeCommand(cmds.Info, translateName(pkgName, pm.Name))
 	case "update", "upgrade", "up":
 		if pkgName == "" {
 			// Upgrade all packages for all detected package managers
 			fmt.Println("Upgrading all packages...")
 			for _, p := range detectedPMs {
 				c, ok := pm_commands[p.Name]
-				if !ok {
+				if !ok || c.UpgradeAll == "" {
 					continue
 				}
 				if !quiet {
@@ -178,26 +272,81 @@ func main() {
 				}
 
 				executeCommand(c.UpgradeAll, "")
+				warnAfterChange(c)
 			}
 		} else {
-			executeCommand(cmds.Upgrade, pkgName)
+			name, c := managerFor(pkgName)
+			executeCommand(c.Upgrade, installedName(pkgName, name))
+			warnAfterChange(c)
 		}
 	case "install", "add":
 		if pkgName == "" {
 			fmt.Println("No package specified.")
 			return
 		}
-		if ok, path := isInstalled(pkgName); ok {
-			fmt.Printf("Package '%s' is already installed at %s\n", pkgName, path)
+		if installingFile {
+			if err := installPackageFile(pkgName); err != nil {
+				fmt.Printf("[error] %v\n", err)
+				os.Exit(1)
+			}
 			return
 		}
-		executeCommand(cmds.Install, pkgName)
+		// the names of some package managers (e.g. AppImage URLs) may contain @ or =
+		name, version := pkgName, ""
+		if !matchesNamePattern(pm.Name, pkgName) {
+			name, version = splitVersion(pkgName)
+		}
+		// runtime managers install versions next to the ones of the system
+		if version == "" && !cmds.Runtimes {
+			if ok, path := isInstalled(name); ok {
+				fmt.Printf("Package '%s' is already installed at %s\n", name, path)
+				return
+			}
+		}
+		r := routeFor(name)
+		if version == "" && r.Use == "" && !slices.Contains(r.Avoid, "flatpak") {
+			if appID, ok := preferFlatpak(pkgName); ok {
+				if !quiet {
+					fmt.Printf("[info] immutable system: installing the flatpak app %s instead of layering it into the system\n", appID)
+				}
+				executeCommand(pm_commands["flatpak"].Install, appID)
+				// uninstall and upgrade find the app ID of the name again
+				recordInstall(pkgName, "flatpak")
+				return
+			}
+		}
+		installWithFallback(name, version, r)
 	case "uninstall", "remove", "rm", "un":
 		if pkgName == "" {
 			fmt.Println("No package specified.")
 			return
 		}
-		executeCommand(cmds.Uninstall, pkgName)
+		name, c := managerFor(pkgName)
+		executeCommand(c.Uninstall, installedName(pkgName, name))
+		forgetInstall(pkgName)
+		warnAfterChange(c)
+	case "resolve":
+		if pkgName == "" {
+			fmt.Println("No package specified.")
+			return
+		}
+		printResolve(pkgName)
+	case "downgrade":
+		if pkgName == "" {
+			fmt.Println("No package specified.")
+			return
+		}
+		name, version := pkgName, targetVersion
+		if version == "" {
+			name, version = splitVersion(pkgName)
+		}
+		downgrade(name, version)
+	case "where":
+		if pkgName == "" {
+			fmt.Println("No package specified.")
+			return
+		}
+		printWhere(pkgName)
 	case "reinstall":
 		// Fallback to install for now, as existing code did
 		fmt.Println("Reinstall not explicitly supported yet. Try install.")
@@ -229,22 +378,24 @@ func main() {
 		if forcesh {
 			const upgradeScript = "https://raw.githubusercontent.com/abanoubha/i/main/scripts/install.sh"
 			fmt.Println("[info] Starting upgrade...")
-			if err := streamToShell(upgradeScript); err != nil {
+			if err := runRemoteScript(upgradeScript); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 			fmt.Println("[info] 'i' is upgraded successfully.")
+			return
 		}
 		installLatestVersion() // Go impl
 	case "selfun", "selfuninstall", "selfdelete":
 		if forcesh {
 			const uninstallScript = "https://raw.githubusercontent.com/abanoubha/i/main/scripts/uninstall.sh"
 			fmt.Println("[info] Starting self delete...")
-			if err := streamToShell(uninstallScript); err != nil {
+			if err := runRemoteScript(uninstallScript); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 			fmt.Println("[info] 'i' is deleted successfully.")
+			return
 		}
 		selfUninstall() // Go impl
 	default:
@@ -295,6 +446,24 @@ i add --pacman vim		# use 'pacman' to install vim program
 i selfup				# upgrade 'i' to the latest version release
 i selfupdate			# upgrade 'i' to the latest version release
 i selfupgrade			# upgrade 'i' to the latest version release
+i selfup --user			# install 'i' into ~/.local/bin, no sudo needed
+i selfup --yes			# upgrade without asking for confirmation
+i selfun --user			# remove 'i' from ~/.local/bin
+
+i doctor				# show the detected system, environment and package managers
+i resolve fd			# show the name of fd in every package manager (e.g. fd-find in apt)
+i where fd				# compare the versions of fd in every detected package manager
+i install ./fd.deb		# install a package file (.deb, .rpm, .apk, .pkg.tar.zst, .flatpakref, .flatpak)
+i install https://example.com/fd.rpm	# download and install a package file
+i install --cargo ripgrep	# install with a language package manager (pipx, uv, cargo, go, npm, gem), no sudo
+i install golang.org/x/tools/gopls	# Go module paths use go install, @scope/name npm
+i install --mise node@20		# install a language runtime with mise, asdf or sdkman
+i install sharkdp/bat	# install the binaries of the latest GitHub release of sharkdp/bat into ~/.local/bin
+i install https://example.com/App-1.0-x86_64.AppImage	# install an AppImage with a menu entry
+i install --appimage owner/repo	# install the AppImage of the latest GitHub release
+i downgrade fd			# choose an older version of fd to install
+i downgrade fd 8.7.0-3	# install version 8.7.0-3 of fd
+i install node@20		# install version 20 of node (or node=20), e.g. brew install node@20, apt install node=20
 
 i --help				# show this information
 i -h					# show this information
@@ -306,24 +475,124 @@ i -v					# show version number
 }
 
 func validateInput(input string) bool {
-	// Allow a-z, A-Z, 0-9, _, -, @, ., +
-	// Some packages have dots (e.g. python3.8) or plus (g++)
-	match, _ := regexp.MatchString(`^[a-zA-Z0-9_\-@.+]+$`, input)
+	// Allow a-z, A-Z, 0-9, _, -, @, ., +, =
+	// Some packages have dots (e.g. python3.8) or plus (g++), @ and = give a version (node@20, curl=7.88.1)
+	match, _ := regexp.MatchString(`^[a-zA-Z0-9_\-@.+=]+$`, input)
 	return match
 }
 
-// detectCommonLinuxPMs appends all found supported package managers to detectedPMs.
-func detectCommonLinuxPMs() {
-	checks := []string{"apt", "dnf", "pacman", "snap", "flatpak", "zypper", "yum", "apk", "xbps-install", "emerge", "nix-env", "brew", "port", "winget", "choco", "scoop"}
-	for _, p := range checks {
-		wrapperName := p
-		if p == "xbps-install" {
-			wrapperName = "xbps"
+// matchesNamePattern reports whether name has the form of package names only pmName takes.
+func matchesNamePattern(pmName, name string) bool {
+	pattern := pm_commands[pmName].NamePattern
+	return pattern != "" && regexp.MustCompile(pattern).MatchString(name)
+}
+
+// pmForName returns the package manager whose NamePattern matches name, or "".
+func pmForName(name string) string {
+	for _, pmName := range slices.Sorted(maps.Keys(pm_commands)) {
+		if matchesNamePattern(pmName, name) {
+			return pmName
+		}
+	}
+	return ""
+}
+
+// detectAvailablePMs appends every supported package manager that runs on
+// this operating system and is installed to detectedPMs, in name order.
+func detectAvailablePMs() {
+	names := slices.Sorted(maps.Keys(pm_commands))
+
+	// probe concurrently, each result keeps the slot of its name to keep the order
+	found := make([]packageManager, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		c := pm_commands[name]
+		if !slices.Contains(c.OS, operatingSystem) {
+			continue
+		}
+		wg.Go(func() {
+			if ok, path := c.probe(); ok {
+				found[i] = packageManager{Name: name, Path: path}
+			}
+		})
+	}
+	wg.Wait()
+
+	for _, p := range found {
+		if p.Name != "" {
+			detectedPMs = append(detectedPMs, p)
+		}
+	}
+}
+
+// osSupported reports whether any package manager runs on this operating system.
+func osSupported() bool {
+	for _, c := range pm_commands {
+		if slices.Contains(c.OS, operatingSystem) {
+			return true
+		}
+	}
+	return false
+}
+
+// probe looks for the executables of the package manager, the first one found wins.
+func (c commands) probe() (bool, string) {
+	for _, bin := range c.Probes {
+		// paths of files that are not executables, e.g. scripts to source
+		if strings.ContainsRune(bin, '/') {
+			if path, ok := expandPath(bin); ok {
+				if _, err := os.Stat(path); err == nil {
+					return true, path
+				}
+			}
+			continue
+		}
+		if ok, path := isInstalled(bin); ok {
+			return true, path
 		}
-		if ok, path := isInstalled(p); ok {
-			detectedPMs = append(detectedPMs, packageManager{Name: wrapperName, Path: path})
+	}
+	return false, ""
+}
+
+// expandPath expands ~/ and environment variables in path, it fails if a variable is not set.
+func expandPath(path string) (string, bool) {
+	if after, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
 		}
+		path = filepath.Join(home, after)
 	}
+	missing := false
+	path = os.Expand(path, func(name string) string {
+		value := os.Getenv(name)
+		missing = missing || value == ""
+		return value
+	})
+	return path, !missing
+}
+
+// systemPM returns the package manager that belongs to the operating system
+// (or Linux distribution), it is used before the others.
+func systemPM() string {
+	if runningEnv.Termux {
+		// Termux has no os-release, its pkg wraps apt
+		return distro_pm["android"]
+	}
+	if operatingSystem != "linux" {
+		return distro_pm[operatingSystem]
+	}
+
+	// Try parsing os-release for ID, then ID_LIKE
+	rel, err := readOSRelease()
+	if err != nil {
+		return ""
+	}
+	if val := immutableSystemPM(rel); val != "" {
+		immutableSystem = true
+		return val
+	}
+	return rel.systemPM()
 }
 
 func detectPM() {
@@ -342,39 +611,36 @@ func detectPM() {
 	}
 
 	operatingSystem = runtime.GOOS
-	switch operatingSystem {
-	case "windows":
+	if operatingSystem == "windows" {
 		fmt.Println("[info] Windows support is experimental.")
-		if ok, path := isInstalled("winget"); ok {
-			detectedPMs = append(detectedPMs, packageManager{Name: "winget", Path: path})
-		}
-		if ok, path := isInstalled("choco"); ok {
-			detectedPMs = append(detectedPMs, packageManager{Name: "choco", Path: path})
-		}
-	case "darwin":
-		if ok, path := isInstalled("brew"); ok {
-			detectedPMs = append(detectedPMs, packageManager{Name: "brew", Path: path})
-		}
-		if ok, path := isInstalled("port"); ok {
-			detectedPMs = append(detectedPMs, packageManager{Name: "port", Path: path})
-		}
-	case "linux":
-		// Try parsing /etc/os-release for ID
-		id := getOSReleaseID()
-		if id != "" {
-			if val, ok := distro_pm[id]; ok {
-				if okP, path := isInstalled(val); okP {
-					detectedPMs = append(detectedPMs, packageManager{Name: val, Path: path})
-					// Don't return, continue to check common ones for co-existing PMs (e.g. invalidating assumption that we only have one)
-					// Actually, distro_pm often maps to the MAIN system PM.
-					// We should probably check common ones too, but deduplicate?
-					// For now, let's keep the logic simple: verify distro PM, then check common ones.
-				}
-			}
+	}
+
+	if c, ok := loadDetectionCache(); ok {
+		immutableSystem = c.Immutable
+		systemPMName = c.SystemPM
+		detectedPMs = c.PMs
+		// priorities may have changed in config since the cache was written
+		sortByPriority(detectedPMs)
+		pm = primaryPM(detectedPMs)
+		return
+	}
+
+	sysPM := systemPM()
+	systemPMName = sysPM
+	if c, ok := pm_commands[sysPM]; ok {
+		if okP, path := c.probe(); okP {
+			detectedPMs = append(detectedPMs, packageManager{Name: sysPM, Path: path})
 		}
-		detectCommonLinuxPMs()
+	}
 
-	default:
+	// continue to check the others for co-existing PMs (e.g. snap and flatpak next to apt)
+	detectAvailablePMs()
+	if immutableSystem {
+		detectedPMs = removeMutablePMs(detectedPMs, sysPM)
+	}
+	detectedPMs = removeUnusablePMs(detectedPMs, runningEnv)
+
+	if len(detectedPMs) == 0 && !osSupported() {
 		fmt.Printf("Unknown operating system: %s\n", operatingSystem)
 	}
 
@@ -388,46 +654,90 @@ func detectPM() {
 		}
 	}
 	detectedPMs = uniquePMs
+	saveDetectionCache(detectedPMs)
+	sortByPriority(detectedPMs)
+	pm = primaryPM(detectedPMs)
+}
 
-	if len(detectedPMs) > 0 {
-		pm = detectedPMs[0]
+// primaryPM is the first package manager by priority that may be the primary
+// one, user-level package managers are never chosen.
+func primaryPM(pms []packageManager) packageManager {
+	for _, p := range pms {
+		if !pm_commands[p.Name].UserLevel {
+			return p
+		}
 	}
+	return packageManager{}
 }
 
-func getOSReleaseID() string {
-	data, err := os.ReadFile("/etc/os-release")
-	if err != nil {
-		return ""
+// pmPriority is the execution priority of a package manager, lower is preferred.
+// "priorities" in config.json overrides the default from pm_commands.
+func pmPriority(name string) int {
+	if p, ok := cfg.Priorities[name]; ok {
+		return p
 	}
-	content := string(data)
-	lines := strings.SplitSeq(content, "\n")
-	for line := range lines {
-		if after, ok := strings.CutPrefix(line, "ID="); ok {
-			id := after
-			return strings.Trim(id, "\"")
-		}
+	// the package manager of the system (e.g. pkg on Termux, which wraps apt) comes first
+	if name == systemPMName {
+		return min(pm_commands[name].Priority, 1)
 	}
-	return ""
+	return pm_commands[name].Priority
+}
+
+// sortByPriority orders package managers by priority, equal priorities keep
+// the detection order, which has the system package manager first.
+func sortByPriority(pms []packageManager) {
+	slices.SortStableFunc(pms, func(a, b packageManager) int {
+		return pmPriority(a.Name) - pmPriority(b.Name)
+	})
 }
 
 func isInstalled(pkg string) (bool, string) {
 	path, err := exec.LookPath(pkg)
-	if errors.Is(err, exec.ErrNotFound) {
+	if err != nil {
 		return false, ""
 	}
 	return true, path
 }
 
+var (
+	errNoCommand         = errors.New("command not defined for this package manager")
+	errSuperUserRequired = errors.New("the command requires super user privileges")
+)
+
 func executeCommand(template string, pkgName string) {
-	if template == "" {
+	err := runCommand(template, pkgName)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, errNoCommand) {
 		fmt.Println("Command not defined for this package manager.")
 		return
 	}
+	if !quiet || errors.Is(err, errSuperUserRequired) {
+		fmt.Printf("[error] %v\n", err)
+	}
+	os.Exit(1)
+}
 
-	cmdStr := template
+// expandTemplate puts pkgName in place of the trailing x of a command template.
+func expandTemplate(template string, pkgName string) string {
 	// if template ends with ".x" or " x" remove "x" and add pkgName
 	if strings.HasSuffix(template, ".x") || strings.HasSuffix(template, " x") {
-		cmdStr = strings.TrimSuffix(template, "x") + pkgName
+		return strings.TrimSuffix(template, "x") + pkgName
+	}
+	return template
+}
+
+// runCommand runs a command template like executeCommand, but returns the error instead of exiting.
+func runCommand(template string, pkgName string) error {
+	if template == "" {
+		return errNoCommand
+	}
+
+	cmdStr := expandTemplate(template, pkgName)
+
+	if strings.HasPrefix(cmdStr, "@") {
+		return runBuiltin(cmdStr)
 	}
 
 	if after, ok := strings.CutPrefix(cmdStr, "sudo "); ok {
@@ -439,13 +749,16 @@ func executeCommand(template string, pkgName string) {
 
 		parts := strings.Fields(cmdStr)
 		if len(parts) == 0 {
-			return
+			return nil
 		}
 
+		if _, err := findEscalator(); err != nil {
+			return fmt.Errorf("%w: %w", errSuperUserRequired, err)
+		}
 		if err := runAsSuperUser(parts...); err != nil {
-			fmt.Println("[error] can not run the command because sudo/doas not found yet the command require super user privilege/permissions", err)
+			return fmt.Errorf("error executing command: %w", err)
 		}
-		return
+		return nil
 	}
 
 	if !quiet {
@@ -454,7 +767,7 @@ func executeCommand(template string, pkgName string) {
 
 	parts := strings.Fields(cmdStr)
 	if len(parts) == 0 {
-		return
+		return nil
 	}
 
 	head := parts[0]
@@ -465,19 +778,64 @@ func executeCommand(template string, pkgName string) {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	err := cmd.Run()
-	if err != nil {
-		if !quiet {
-			fmt.Printf("[error] error executing command: %v\n", err)
-		}
-		os.Exit(1)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error executing command: %w", err)
+	}
+	return nil
+}
+
+// commandSucceeds runs a read-only command template silently (never as super user) and reports whether it exited with 0.
+func commandSucceeds(template string, pkgName string) bool {
+	parts := strings.Fields(strings.TrimPrefix(expandTemplate(template, pkgName), "sudo "))
+	if len(parts) == 0 {
+		return false
+	}
+	return exec.Command(parts[0], parts[1:]...).Run() == nil
+}
+
+// askConfirmation prints the question and waits for a yes/no answer, anything but yes means no.
+func askConfirmation(question string) bool {
+	if assumeYes {
+		fmt.Printf("%s [y/N] yes\n", question)
+		return true
 	}
+	return askUser(question)
+}
+
+// askUser asks like askConfirmation, but --yes does not answer it, for
+// questions that must be answered by a person (running a downloaded script).
+func askUser(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	reader := bufio.NewReader(os.Stdin)
+	ans, _ := reader.ReadString('\n')
+	ans = strings.ToLower(strings.TrimSpace(ans))
+
+	return ans == "y" || ans == "yes"
 }
 
-// fetches a remote shell script and pipes it directly to sh.
-func streamToShell(url string) error {
+// maxScriptSize is the largest remote script we accept, ours are a few KB.
+const maxScriptSize = 1 << 20
+
+// scriptSource is the only place remote scripts are run from, the scripts directory of this repository
+const scriptSource = "https://raw.githubusercontent.com/" + GithubRepo + "/main/scripts/"
+
+// runRemoteScript downloads a shell script of this repository to a temp file,
+// checks that it is a complete shell script, shows it with its sha256 and runs
+// it with sh only after the user agrees. The scripts change with every commit
+// to main, so there is no checksum to compare with.
+func runRemoteScript(url string) error {
+	if !strings.HasPrefix(url, scriptSource) {
+		return fmt.Errorf("refusing to run a script from %s, only %s is allowed", url, scriptSource)
+	}
 	client := &http.Client{
 		Timeout: 60 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !strings.HasPrefix(req.URL.String(), scriptSource) {
+				return fmt.Errorf("refusing the redirect to %s", req.URL)
+			}
+			return nil
+		},
 	}
 
 	resp, err := client.Get(url)
@@ -490,11 +848,46 @@ func streamToShell(url string) error {
 		return fmt.Errorf("server returned non-200 status: %d %s", resp.StatusCode, resp.Status)
 	}
 
-	cmd := exec.Command("sh")
+	script, err := io.ReadAll(io.LimitReader(resp.Body, maxScriptSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download script: %w", err)
+	}
+	if len(script) > maxScriptSize {
+		return fmt.Errorf("script is larger than %d bytes, refusing to run it", maxScriptSize)
+	}
+	if !bytes.HasPrefix(script, []byte("#!")) {
+		return fmt.Errorf("downloaded file is not a shell script (no #! line)")
+	}
+
+	tmpFile, err := os.CreateTemp("", "i-script-*.sh")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(script); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write script: %w", err)
+	}
+	tmpFile.Close()
+
+	// parse the script without running it to catch truncated or garbled downloads
+	if out, err := exec.Command("sh", "-n", tmpFile.Name()).CombinedOutput(); err != nil {
+		return fmt.Errorf("script failed the syntax check: %v\n%s", err, out)
+	}
+
+	fmt.Printf("[info] downloaded %s\n[info] sha256: %x\n\n", url, sha256.Sum256(script))
+	fmt.Println(string(script))
 
-	// pipe streams
-	cmd.Stdin = resp.Body
+	if assumeYes {
+		fmt.Println("[info] --yes does not answer whether to run a downloaded script")
+	}
+	if !askUser("Run this script?") {
+		return fmt.Errorf("aborted by user")
+	}
 
+	cmd := exec.Command("sh", tmpFile.Name())
+	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
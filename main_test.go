@@ -1,7 +1,17 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -14,12 +24,12 @@ func TestCommandGeneration(t *testing.T) {
 		expectedTemplate string
 		expectedCmd      string
 	}{
-		{"dnf", "install", "dnf install -y x", "dnf install -y testpkg"},
-		{"pacman", "install", "pacman -S --noconfirm x", "pacman -S --noconfirm testpkg"},
+		{"dnf", "install", "sudo dnf install -y x", "sudo dnf install -y testpkg"},
+		{"pacman", "install", "sudo pacman -S --noconfirm x", "sudo pacman -S --noconfirm testpkg"},
 		{"yum", "search", "yum search x", "yum search testpkg"},
 		{"zypper", "info", "zypper info x", "zypper info testpkg"},
-		{"apk", "upgrade", "apk add --upgrade x", "apk add --upgrade testpkg"},
-		{"xbps", "uninstall", "xbps-remove -y x", "xbps-remove -y testpkg"},
+		{"apk", "upgrade", "sudo apk add --upgrade x", "sudo apk add --upgrade testpkg"},
+		{"xbps", "uninstall", "sudo xbps-remove -y x", "sudo xbps-remove -y testpkg"},
 		{"nix-env", "install", "nix-env -iA nixpkgs.x", "nix-env -iA nixpkgs.testpkg"},
 		// Add checks for multi-word commands or flags
 		{"apt", "list", "apt list --installed", "apt list --installed"},
@@ -69,3 +79,745 @@ func TestCommandGeneration(t *testing.T) {
 		}
 	}
 }
+
+func TestAssetNames(t *testing.T) {
+	platforms := parsePlatforms(platformsTable)
+	if len(platforms) == 0 {
+		t.Fatal("no platforms found in scripts/platforms.txt")
+	}
+
+	// asset names of already published releases must not change
+	tests := []struct {
+		goos, goarch string
+		expected     string
+	}{
+		{"linux", "amd64", "i-linux-x64"},
+		{"linux", "arm64", "i-linux-arm64"},
+		{"darwin", "amd64", "i-macos-intel-x64"},
+		{"darwin", "arm64", "i-macos-apple-silicon-arm64"},
+		{"windows", "amd64", "i-windows-x64"},
+		{"freebsd", "riscv64", "i-freebsd-riscv64"},
+		{"openbsd", "arm64", "i-openbsd-arm64"},
+	}
+
+	for _, tt := range tests {
+		asset, err := assetFor(tt.goos, tt.goarch)
+		if err != nil {
+			t.Errorf("%s/%s: %v", tt.goos, tt.goarch, err)
+			continue
+		}
+		if asset != tt.expected {
+			t.Errorf("%s/%s asset mismatch: got %q, want %q", tt.goos, tt.goarch, asset, tt.expected)
+		}
+	}
+
+	if _, err := assetFor("plan9", "386"); err == nil {
+		t.Error("expected an error for an unsupported platform")
+	}
+}
+
+func TestChangelogSections(t *testing.T) {
+	changelog := `# change log for i
+
+## v25.12.20
+
+- old
+
+## v260201
+
+- installed
+
+## v260203
+
+- new one
+
+## working on
+
+- unreleased
+`
+	got := changelogSections(changelog, "260201", "v260203.exe")
+	want := "## v260203\n\n- new one"
+	if got != want {
+		t.Errorf("changelogSections mismatch: got %q, want %q", got, want)
+	}
+
+	if got := changelogSections(changelog, "251220", "260203"); !regexp.MustCompile(`(?s)v260201.*v260203`).MatchString(got) {
+		t.Errorf("expected both newer sections, got %q", got)
+	}
+}
+
+func TestDistroPMsHaveCommands(t *testing.T) {
+	for distro, pmName := range distro_pm {
+		if _, ok := pm_commands[pmName]; !ok {
+			t.Errorf("distro %q maps to %q which has no pm_commands entry", distro, pmName)
+		}
+	}
+}
+
+func TestEveryPMIsDetectable(t *testing.T) {
+	for name, c := range pm_commands {
+		if name == "i" {
+			continue
+		}
+		if len(c.Probes) == 0 && !c.Builtin {
+			t.Errorf("%s: no probe executables, it can never be detected", name)
+		}
+		if len(c.OS) == 0 {
+			t.Errorf("%s: no operating systems", name)
+		}
+	}
+}
+
+func TestNameTable(t *testing.T) {
+	// ignore the names.json of the user
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("AppData", home)
+	loadNames()
+	if names.Version < 1 {
+		t.Errorf("names.json has no version")
+	}
+	for canonical, perPM := range names.Packages {
+		for pmName, name := range perPM {
+			if _, ok := pm_commands[pmName]; !ok {
+				t.Errorf("%s: unknown package manager %q", canonical, pmName)
+			}
+			if name == "" {
+				t.Errorf("%s: empty name for %s", canonical, pmName)
+			}
+		}
+	}
+
+	if got := translateName("fd", "apt"); got != "fd-find" {
+		t.Errorf("translateName(fd, apt): got %q, want %q", got, "fd-find")
+	}
+	if got := translateName("vim", "apt"); got != "vim" {
+		t.Errorf("translateName(vim, apt): got %q, want %q", got, "vim")
+	}
+}
+
+func TestAvailableCheck(t *testing.T) {
+	tests := []struct {
+		pm, name, plain string
+		template, query string
+	}{
+		{"dnf", "@development-tools", "@development-tools", "dnf group info x", "development-tools"},
+		{"dnf", "fd-find", "fd-find", "dnf info -q x", "fd-find"},
+		{"apt", "curl=7.88.1", "curl", "apt-cache show x", "curl=7.88.1"},
+		{"snap", "hugo --channel=0.135", "hugo", pm_commands["snap"].Available, "hugo"},
+		{"npm", "@angular/cli", "@angular/cli", "", "angular/cli"},
+	}
+	for _, tt := range tests {
+		template, query := availableCheck(tt.pm, tt.name, tt.plain)
+		if template != tt.template || query != tt.query {
+			t.Errorf("availableCheck(%s, %q) = %q, %q, want %q, %q", tt.pm, tt.name, template, query, tt.template, tt.query)
+		}
+	}
+}
+
+func TestVersionParsers(t *testing.T) {
+	tests := []struct {
+		name string
+		got  pkgVersions
+		want pkgVersions
+	}{
+		{"apt", parseAptPolicy("fd-find:\n  Installed: (none)\n  Candidate: 8.7.0-3+b1\n  Version table:\n     8.7.0-3+b1 500\n"), pkgVersions{Available: "8.7.0-3+b1"}},
+		{"brew formula", parseBrewInfo(`{"formulae":[{"versions":{"stable":"10.2.0"},"installed":[{"version":"10.1.0"}]}],"casks":[]}`), pkgVersions{Available: "10.2.0", Installed: "10.1.0"}},
+		{"brew cask", parseBrewInfo(`{"formulae":[],"casks":[{"version":"1.95.0","installed":null}]}`), pkgVersions{Available: "1.95.0"}},
+		{"snap", parseSnapInfo("name: hugo\nchannels:\n  latest/stable:    0.136.5 2024-10-24 (21090) 60MB -\n  latest/candidate: ^\ninstalled:          0.135.0            (20987) 60MB -\n"), pkgVersions{Available: "0.136.5", Installed: "0.135.0"}},
+		{"dnf", parseDnfInfo("Installed Packages\nName         : fd-find\nVersion      : 9.0.0\nRelease      : 4.fc40\nAvailable Packages\nName         : fd-find\nVersion      : 10.2.0\nRelease      : 1.fc40\n"), pkgVersions{Available: "10.2.0-1.fc40", Installed: "9.0.0-4.fc40"}},
+		{"apk", parseApkList("fd-10.2.0-r0 x86_64 {fd} (Apache-2.0 OR MIT) [installed]\nfd-doc-10.2.0-r0 x86_64 {fd} (Apache-2.0 OR MIT)\n", "fd"), pkgVersions{Available: "10.2.0-r0", Installed: "10.2.0-r0"}},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// TestQueryTemplates checks that the templates of the version queries put the
+// package name in, a template must end in " x" or ".x".
+func TestQueryTemplates(t *testing.T) {
+	oldOutput := queryOutput
+	defer func() { queryOutput = oldOutput }()
+
+	var commands [][]string
+	queryOutput = func(name string, args ...string) ([]byte, error) {
+		commands = append(commands, append([]string{name}, args...))
+		return nil, errors.New("not run")
+	}
+	// some queries list all the packages as well (flatpak list), one must ask for it
+	check := func(what string) {
+		t.Helper()
+		asks := slices.ContainsFunc(commands, func(cmd []string) bool {
+			return slices.ContainsFunc(cmd, func(arg string) bool { return strings.Contains(arg, "fd-find") })
+		})
+		if len(commands) > 0 && !asks {
+			t.Errorf("%s: %q do not ask for the package", what, commands)
+		}
+		commands = nil
+	}
+	for name, query := range versionQueries {
+		query("fd-find")
+		check("versionQueries[" + name + "]")
+	}
+	for name, d := range downgraders {
+		d.versions("fd-find")
+		check("downgraders[" + name + "]")
+	}
+}
+
+func TestRoutingRules(t *testing.T) {
+	oldCfg, oldPM, oldDetected, oldQuiet := cfg, pm, detectedPMs, quiet
+	defer func() { cfg, pm, detectedPMs, quiet = oldCfg, oldPM, oldDetected, oldQuiet }()
+
+	quiet = true
+	detectedPMs = []packageManager{{Name: "apt"}, {Name: "brew"}, {Name: "snap"}}
+	pm = detectedPMs[0]
+	cfg.Rules = []routingRule{
+		{Match: "node*", Use: "brew"},
+		{Match: "docker*", Avoid: []string{"snap"}},
+		{Match: "kubectl", Use: "nix-env"}, // not detected, ignored
+	}
+
+	tests := []struct {
+		pkg  string
+		want []string
+	}{
+		{"nodejs", []string{"brew", "apt", "snap"}},
+		{"docker-compose", []string{"apt", "brew"}},
+		{"kubectl", []string{"apt", "brew", "snap"}},
+		{"vim", []string{"apt", "brew", "snap"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range routeFor(tt.pkg).candidates() {
+			got = append(got, p.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.pkg, got, tt.want)
+		}
+	}
+
+	// gui rules need a flatpak app that uses a display, git has one that does not
+	oldFlatpak := flatpakOutput
+	defer func() { flatpakOutput = oldFlatpak }()
+	flatpakOutput = func(args ...string) ([]byte, error) {
+		switch strings.Join(args, " ") {
+		case "search --columns=application,name gimp":
+			return []byte("org.gimp.GIMP\tGNU Image Manipulation Program\n"), nil
+		case "search --columns=application,name git":
+			return []byte("io.example.git\tgit\n"), nil
+		case "remotes --columns=name":
+			return []byte("flathub\n"), nil
+		case "remote-info --show-metadata flathub org.gimp.GIMP":
+			return []byte("[Context]\nsockets=x11;wayland;\n"), nil
+		case "remote-info --show-metadata flathub io.example.git":
+			return []byte("[Context]\nshared=network;\n"), nil
+		}
+		return nil, errors.New("unexpected flatpak command")
+	}
+	detectedPMs = append(detectedPMs, packageManager{Name: "flatpak"})
+	cfg.Rules = []routingRule{{GUI: true, Use: "flatpak"}}
+	if r := routeFor("gimp"); r.Use != "flatpak" || r.AppID != "org.gimp.GIMP" {
+		t.Errorf("gimp: got %+v, want flatpak org.gimp.GIMP", r)
+	}
+	if r := routeFor("git"); r.Use != "" {
+		t.Errorf("git is no desktop app, got %+v", r)
+	}
+}
+
+func TestVersionSyntax(t *testing.T) {
+	tests := []struct {
+		pkg, pm, want string
+	}{
+		{"node@20", "brew", "node@20"},
+		{"node@20", "snap", "node --channel=20"},
+		{"curl=7.88.1-10", "apt", "curl=7.88.1-10"},
+		{"curl=7.88.1", "dnf", "curl-7.88.1"},
+		{"nodejs@20", "nix-env", "nodejs_20"},
+		{"vim", "pacman", "vim"},
+	}
+	for _, tt := range tests {
+		name, version := splitVersion(tt.pkg)
+		got, err := pinnedName(pm_commands[tt.pm], name, version)
+		if err != nil || got != tt.want {
+			t.Errorf("%s with %s: got %q (%v), want %q", tt.pkg, tt.pm, got, err, tt.want)
+		}
+	}
+
+	if _, err := pinnedName(pm_commands["pacman"], "vim", "9.1"); err == nil {
+		t.Error("pacman can not pin versions, want an error")
+	}
+}
+
+func TestDowngradeVersions(t *testing.T) {
+	apt := parseAptVersionTable("curl:\n  Installed: 7.88.1-10+deb12u14\n  Candidate: 7.88.1-10+deb12u14\n  Version table:\n *** 7.88.1-10+deb12u14 500\n        500 http://deb.debian.org/debian-security bookworm-security/main amd64 Packages\n        100 /var/lib/dpkg/status\n     7.88.1-10+deb12u5 500\n        500 http://deb.debian.org/debian bookworm/main amd64 Packages\n")
+	if want := []string{"7.88.1-10+deb12u14", "7.88.1-10+deb12u5"}; !slices.Equal(apt, want) {
+		t.Errorf("apt: got %v, want %v", apt, want)
+	}
+
+	dnf := parseDnfList("Installed Packages\nfd-find.x86_64    9.0.0-4.fc40     @fedora\nAvailable Packages\nfd-find.x86_64    9.0.0-4.fc40     fedora\nfd-find.x86_64    10.2.0-1.fc40    updates\n", "fd-find")
+	if want := []string{"10.2.0-1.fc40", "9.0.0-4.fc40"}; !slices.Equal(dnf, want) {
+		t.Errorf("dnf: got %v, want %v", dnf, want)
+	}
+
+	pacman := parsePacmanFiles([]string{
+		"/var/cache/pacman/pkg/fd-10.2.0-1-x86_64.pkg.tar.zst",
+		"/var/cache/pacman/pkg/fd-10.2.0-1-x86_64.pkg.tar.zst.sig",
+		"/var/cache/pacman/pkg/fd-9.0.0-1-x86_64.pkg.tar.zst",
+		"/var/cache/pacman/pkg/fd-9.10.0-1-x86_64.pkg.tar.zst",
+		"/var/cache/pacman/pkg/fd-find-1.0-1-x86_64.pkg.tar.zst",
+	}, "fd")
+	var versions []string
+	for _, f := range pacman {
+		versions = append(versions, f.version)
+	}
+	if want := []string{"10.2.0-1", "9.10.0-1", "9.0.0-1"}; !slices.Equal(versions, want) {
+		t.Errorf("pacman: got %v, want %v", versions, want)
+	}
+
+	// older first, from the pacman vercmp manual
+	ordered := []string{"1.0a", "1.0alpha", "1.0b", "1.0beta", "1.0p", "1.0pre", "1.0rc", "1.0", "1.0.a", "1.0.1", "1.0.2-1", "1.0.2-2", "1.0.10", "1:0.9"}
+	for i := range ordered {
+		for j := range ordered {
+			if got, want := vercmp(ordered[i], ordered[j]), cmp.Compare(i, j); got != want {
+				t.Errorf("vercmp(%q, %q) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	for _, tt := range []struct{ installed, version, want string }{
+		{"10.2.0-1.fc40", "9.0.0-1.fc40", "sudo dnf downgrade -y x"},
+		{"10.2.0-1.fc40", "10.2.0-1.fc40", "sudo dnf install -y x"},
+		{"9.0.0-1.fc40", "10.2.0-1.fc40", "sudo dnf install -y x"},
+		{"", "9.0.0-1.fc40", "sudo dnf install -y x"},
+		{"1:1.0-1", "2.0-1", "sudo dnf downgrade -y x"},
+	} {
+		if got := rpmVersionCommand("dnf", tt.installed, tt.version); got != tt.want {
+			t.Errorf("rpmVersionCommand(dnf, %q, %q) = %q, want %q", tt.installed, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestPackageFormats(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		file    string
+		content string
+		want    string // "" for an error
+	}{
+		{"fd.deb", "!<arch>\ndebian-binary   ", "deb"},
+		{"fd.rpm", "\xed\xab\xee\xdb\x03\x00", "rpm"},
+		{"download", "\xed\xab\xee\xdb\x03\x00", "rpm"},
+		{"fd-10.2.0-1-x86_64.pkg.tar.zst", "\x28\xb5\x2f\xfd", "pacman"},
+		{"app.flatpakref", "[Flatpak Ref]\nName=org.gimp.GIMP\n", "flatpakref"},
+		{"android.apk", "PK\x03\x04", ""},
+		{"fake.deb", "<html>", ""},
+		{"notes.txt", "hello", ""},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := detectFormat(path)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: got format %s, want an error", tt.file, f.Name)
+			}
+			continue
+		}
+		if err != nil || f.Name != tt.want {
+			t.Errorf("%s: got %q (%v), want %q", tt.file, f.Name, err, tt.want)
+		}
+	}
+
+	download := filepath.Join(dir, "download")
+	for arg, want := range map[string]bool{"./fd.deb": true, "fd.deb": true, "https://example.com/fd": true, download: true, dir: false, "./missing": false, "user/tap/formula": false, "fd": false, "python3.12": false, "node@20": false} {
+		if got := isPackageFile(arg); got != want {
+			t.Errorf("isPackageFile(%q) = %v, want %v", arg, got, want)
+		}
+	}
+	for arg, want := range map[string]bool{"./missing.deb": true, "../a/b": true, "/tmp/x": true, "fd.rpm": true, "owner/repo": false, "https://example.com/a.AppImage": false} {
+		if got := isPathLike(arg); got != want {
+			t.Errorf("isPathLike(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
+
+func TestPmForName(t *testing.T) {
+	for name, want := range map[string]string{
+		"sharkdp/bat":                       "github",
+		"cli/cli":                           "github",
+		"mvdan.cc/gofumpt":                  "go",
+		"sigs.k8s.io/kind":                  "go",
+		"gopkg.in/yaml.v3":                  "go",
+		"golang.org/x/tools/gopls@latest":   "go",
+		"@angular/cli":                      "npm",
+		"https://example.com/nvim.appimage": "appimage",
+		"ripgrep":                           "",
+	} {
+		if got := pmForName(name); got != want {
+			t.Errorf("pmForName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestPickReleaseAsset(t *testing.T) {
+	bat := []string{
+		"bat-musl_0.24.0_amd64.deb",
+		"bat-v0.24.0-aarch64-unknown-linux-gnu.tar.gz",
+		"bat-v0.24.0-arm-unknown-linux-gnueabihf.tar.gz",
+		"bat-v0.24.0-i686-unknown-linux-musl.tar.gz",
+		"bat-v0.24.0-x86_64-apple-darwin.tar.gz",
+		"bat-v0.24.0-x86_64-pc-windows-msvc.zip",
+		"bat-v0.24.0-x86_64-unknown-linux-gnu.tar.gz",
+		"bat-v0.24.0-x86_64-unknown-linux-musl.tar.gz",
+		"bat-v0.24.0-x86_64-unknown-linux-musl.tar.gz.sha256",
+	}
+	jq := []string{"jq-linux-amd64", "jq-linux-arm64", "jq-macos-arm64", "jq-windows-amd64.exe", "sha256sum.txt"}
+
+	tests := []struct {
+		names        []string
+		goos, goarch string
+		want         string
+	}{
+		{bat, "linux", "amd64", "bat-v0.24.0-x86_64-unknown-linux-musl.tar.gz"},
+		{bat, "linux", "arm64", "bat-v0.24.0-aarch64-unknown-linux-gnu.tar.gz"},
+		{bat, "linux", "arm", "bat-v0.24.0-arm-unknown-linux-gnueabihf.tar.gz"},
+		{bat, "linux", "386", "bat-v0.24.0-i686-unknown-linux-musl.tar.gz"},
+		{bat, "darwin", "amd64", "bat-v0.24.0-x86_64-apple-darwin.tar.gz"},
+		{bat, "windows", "amd64", "bat-v0.24.0-x86_64-pc-windows-msvc.zip"},
+		{bat, "darwin", "arm64", ""},
+		{jq, "linux", "arm64", "jq-linux-arm64"},
+		{jq, "darwin", "arm64", "jq-macos-arm64"},
+		{jq, "windows", "amd64", "jq-windows-amd64.exe"},
+	}
+	for _, tt := range tests {
+		got, _ := pickReleaseAsset(tt.names, tt.goos, tt.goarch)
+		if got != tt.want {
+			t.Errorf("%s/%s: got %q, want %q", tt.goos, tt.goarch, got, tt.want)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive := func(name string, files map[string]string) string {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for file, content := range files {
+			tw.WriteHeader(&tar.Header{Name: file, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(content))
+		}
+		tw.Close()
+		gz.Close()
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, buf.Bytes(), 0644)
+		return path
+	}
+
+	out := filepath.Join(dir, "out")
+	archive := writeArchive("tool.tar.gz", map[string]string{"tool-v1/tool": "\x7fELF binary", "tool-v1/README.md": "# tool"})
+	if err := extractArchive(archive, "tool", out); err != nil {
+		t.Fatal(err)
+	}
+	binaries, err := findExecutables(out)
+	if err != nil || len(binaries) != 1 || filepath.Base(binaries[0]) != "tool" {
+		t.Errorf("got executables %v (%v), want tool", binaries, err)
+	}
+
+	archive = writeArchive("evil.tar.gz", map[string]string{"../escape": "\x7fELF"})
+	if err := extractArchive(archive, "evil", out); err == nil {
+		t.Error("an entry outside of the archive was extracted")
+	}
+}
+
+func TestAppImageKey(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	registry := map[string]appImage{"nvim": {Source: "neovim/nvim"}, "obsidian": {Source: "https://example.com/Obsidian-1.4.16.AppImage"}}
+	if err := writeState(appImageRegistryFile, registry); err != nil {
+		t.Fatal(err)
+	}
+	for arg, want := range map[string]string{"nvim": "nvim", "neovim/nvim": "nvim", "https://example.com/Obsidian-1.4.16.AppImage": "obsidian", "other/repo": "other/repo"} {
+		if got := appImageKey(arg); got != want {
+			t.Errorf("appImageKey(%q) = %q, want %q", arg, got, want)
+		}
+	}
+}
+
+func TestSquashfsOffset(t *testing.T) {
+	file := make([]byte, 300)
+	copy(file, "\x7fELF\x02\x01\x01")
+	file[0x28] = 100 // e_shoff
+	file[0x3a] = 64  // e_shentsize
+	file[0x3c] = 2   // e_shnum
+	copy(file[228:], "hsqs")
+	if got, err := squashfsOffset(bytes.NewReader(file), file[:64]); err != nil || got != 228 {
+		t.Errorf("squashfsOffset = %d, %v, want 228", got, err)
+	}
+	copy(file[228:], "\x00\x00\x00\x00")
+	if _, err := squashfsOffset(bytes.NewReader(file), file[:64]); err == nil {
+		t.Error("squashfsOffset found a squashfs in a plain ELF file")
+	}
+}
+
+func TestAppImageName(t *testing.T) {
+	for file, want := range map[string]string{
+		"Obsidian-1.4.16.AppImage":                                "obsidian",
+		"https://example.com/nvim.appimage":                       "nvim",
+		"balenaEtcher-1.18.11-x64.AppImage":                       "balenaetcher",
+		"/tmp/Joplin_2.13.9.AppImage":                             "joplin",
+		"LM-Studio-x86_64.AppImage":                               "lm-studio",
+		"https://example.com/dl/kdenlive-24.08.3-x86_64.AppImage": "kdenlive",
+	} {
+		if got := appImageName(file); got != want {
+			t.Errorf("appImageName(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
+
+func TestUserLevelPMs(t *testing.T) {
+	for name, c := range pm_commands {
+		if !c.UserLevel {
+			continue
+		}
+		for _, tmpl := range []string{c.Install, c.Uninstall, c.Upgrade, c.UpgradeAll, c.ListInstalled, c.UpdateIndex} {
+			if needsSuperUser(tmpl) {
+				t.Errorf("%s: user-level command %q needs sudo", name, tmpl)
+			}
+		}
+	}
+
+	pms := []packageManager{{Name: "cargo"}, {Name: "npm"}, {Name: "apt"}, {Name: "flatpak"}}
+	if got := primaryPM(pms).Name; got != "apt" {
+		t.Errorf("primaryPM = %q, want apt", got)
+	}
+	if got := primaryPM(pms[:2]).Name; got != "" {
+		t.Errorf("primaryPM of user-level package managers = %q, want none", got)
+	}
+
+	crates := parseCargoList("ripgrep v14.1.1:\n    rg\nfd-find v10.2.0:\n    fd\n")
+	if !slices.Equal(crates, []string{"ripgrep", "fd-find"}) {
+		t.Errorf("parseCargoList = %v", crates)
+	}
+	b := parseGoVersion("/home/user/go/bin/gopls: go1.23.2\n\tpath\tgolang.org/x/tools/gopls\n\tmod\tgolang.org/x/tools/gopls\tv0.16.2\th1:abc=\n")
+	if b.Path != "golang.org/x/tools/gopls" || b.Version != "v0.16.2" {
+		t.Errorf("parseGoVersion = %+v", b)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("SDKMAN_DIR", "/opt/sdkman")
+	t.Setenv("EMPTY", "")
+	for path, want := range map[string]string{
+		"~/.sdkman/bin/sdkman-init.sh":     "/home/me/.sdkman/bin/sdkman-init.sh",
+		"$SDKMAN_DIR/bin/sdkman-init.sh":   "/opt/sdkman/bin/sdkman-init.sh",
+		"${SDKMAN_DIR}/bin/sdkman-init.sh": "/opt/sdkman/bin/sdkman-init.sh",
+		"$EMPTY/bin/sdkman-init.sh":        "",
+	} {
+		got, ok := expandPath(path)
+		if !ok {
+			got = ""
+		}
+		if got != want {
+			t.Errorf("expandPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestAsdfVersions(t *testing.T) {
+	got := asdfVersions("  20.18.1\n *22.12.0\n")
+	if want := []string{"20.18.1", "22.12.0"}; !slices.Equal(got, want) {
+		t.Errorf("asdfVersions = %q, want %q", got, want)
+	}
+	if got := asdfVersions("  No versions installed"); len(got) != 0 {
+		t.Errorf("asdfVersions = %q, want none", got)
+	}
+}
+
+func TestUsesDisplay(t *testing.T) {
+	for metadata, want := range map[string]bool{
+		"[Application]\nname=org.gimp.GIMP\n\n[Context]\nshared=network;ipc;\nsockets=x11;wayland;pulseaudio;\n": true,
+		"[Context]\nsockets=fallback-x11;wayland;\n":                                                             true,
+		"[Application]\nname=org.freedesktop.Piper\n\n[Context]\nshared=network;\nsockets=pulseaudio;\n":         false,
+		"[Application]\nname=io.github.cli\n":                                                                    false,
+	} {
+		if got := usesDisplay(metadata); got != want {
+			t.Errorf("usesDisplay(%q) = %v, want %v", metadata, got, want)
+		}
+	}
+}
+
+func TestMatchFlatpakApp(t *testing.T) {
+	out := "org.gimp.GIMP\tGNU Image Manipulation Program\ncom.obsproject.Studio\tOBS Studio\n"
+	for pkg, want := range map[string]string{"gimp": "org.gimp.GIMP", "OBS Studio": "com.obsproject.Studio", "vim": ""} {
+		if got, _ := matchFlatpakApp(out, pkg); got != want {
+			t.Errorf("matchFlatpakApp(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestDetectionCacheEnvironment(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	saved := runningEnv
+	defer func() { runningEnv = saved }()
+
+	runningEnv = environment{Systemd: true}
+	saveDetectionCache([]packageManager{{Name: "snap"}})
+	if _, ok := loadDetectionCache(); !ok {
+		t.Fatal("the cache is not valid right after saving it")
+	}
+	runningEnv = environment{}
+	if _, ok := loadDetectionCache(); ok {
+		t.Error("the cache survived a change of the environment")
+	}
+}
+
+func TestDetectionCacheWatchesProbeFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SDKMAN_DIR", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	saveDetectionCache(nil)
+	script := filepath.Join(home, ".sdkman", "bin", "sdkman-init.sh")
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(script, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := loadDetectionCache(); ok {
+		t.Error("the cache survived installing sdkman")
+	}
+}
+
+func TestPriorities(t *testing.T) {
+	savedSystemPM, savedPriorities := systemPMName, cfg.Priorities
+	defer func() { systemPMName, cfg.Priorities = savedSystemPM, savedPriorities }()
+
+	tests := []struct {
+		name       string
+		systemPM   string
+		priorities map[string]int
+		pms        []string // in detection order, the system package manager first
+		want       string
+	}{
+		{"debian", "apt", nil, []string{"apt", "flatpak", "snap"}, "apt"},
+		{"termux", "pkg", nil, []string{"pkg", "apt"}, "pkg"},
+		{"termux with apt in config", "pkg", map[string]int{"apt": 0}, []string{"pkg", "apt"}, "apt"},
+		{"macOS with brew", "brew", nil, []string{"brew", "port"}, "brew"},
+	}
+	for _, tt := range tests {
+		systemPMName, cfg.Priorities = tt.systemPM, tt.priorities
+		var pms []packageManager
+		for _, name := range tt.pms {
+			pms = append(pms, packageManager{Name: name})
+		}
+		sortByPriority(pms)
+		if got := primaryPM(pms).Name; got != tt.want {
+			t.Errorf("%s: primary is %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteDesktopEntry(t *testing.T) {
+	entry := []string{"[Desktop Entry]", "Name=Obsidian", "Exec=AppRun --no-sandbox %U", "TryExec=obsidian", "", "[Desktop Action New]", "Exec=AppRun --new", "Icon=other"}
+	got := rewriteDesktopEntry(entry, "/home/me/My Apps/i/appimages/obsidian.AppImage", "/home/me/My Apps/i/appimages/obsidian.png")
+	want := []string{
+		"[Desktop Entry]",
+		"Icon=/home/me/My Apps/i/appimages/obsidian.png",
+		"Name=Obsidian",
+		`Exec="/home/me/My Apps/i/appimages/obsidian.AppImage" --no-sandbox %U`,
+		"",
+		"[Desktop Action New]",
+		`Exec="/home/me/My Apps/i/appimages/obsidian.AppImage" --new`,
+		"Icon=other",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for arg, want := range map[string]string{
+		"/opt/apps/nvim.AppImage": "/opt/apps/nvim.AppImage",
+		`/home/a "b"/x`:           `"/home/a \\"b\\"/x"`,
+		"/home/$HOME/100%":        `"/home/\\$HOME/100%%"`,
+	} {
+		if got := desktopExecArg(arg); got != want {
+			t.Errorf("desktopExecArg(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
+
+func TestIsNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v260210", "260203", true},
+		{"260203", "v260203", false},
+		{"v260203", "v260203", false},
+		{"v260201", "260203", false},
+		{"v26.01.30", "260203", false}, // the old dotted tags are older
+		{"1.10", "1.9", true},
+		{"1.9", "1.10", false},
+		{"1.2.1", "1.2", true},
+		{"1.2.0", "1.2", false},
+		{"v260210-rc1", "260203", false}, // pre-releases are not offered
+		{"v260203", "260203-rc1", true},
+		{"latest", "260203", false},
+		{"260210", "dev", false},
+	}
+	for _, tt := range tests {
+		if got := isNewerVersion(tt.latest, tt.current); got != tt.want {
+			t.Errorf("isNewerVersion(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+
+	for _, latest := range []string{"260210", "260210.exe", "v260210"} {
+		if got, ok := updateAvailable(latest, "260203"); !ok || got != "260210" {
+			t.Errorf("updateAvailable(%q) = %q, %v, want 260210, true", latest, got, ok)
+		}
+	}
+	if _, ok := updateAvailable("260203.exe", "260203"); ok {
+		t.Error("updateAvailable offers the installed version")
+	}
+
+	for s, want := range map[string]string{"v260203": "260203", "260203": "260203", "v260203.exe": "260203", "v26.01.30": "26", "latest": ""} {
+		if got := releaseNumber(s); got != want {
+			t.Errorf("releaseNumber(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestRemoteScriptSource(t *testing.T) {
+	err := runRemoteScript("https://example.com/install.sh")
+	if err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("runRemoteScript of another host: got %v, want a refusal", err)
+	}
+}
+
+func TestRemoveUnusablePMs(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("snap and WSL are Linux only")
+	}
+	pms := []packageManager{{Name: "apt", Path: "/usr/bin/apt"}, {Name: "snap", Path: "/usr/bin/snap"}, {Name: "npm", Path: "/mnt/c/Program Files/nodejs/npm"}}
+	names := func(pms []packageManager) []string {
+		var names []string
+		for _, p := range pms {
+			names = append(names, p.Name)
+		}
+		return names
+	}
+	if got := names(removeUnusablePMs(slices.Clone(pms), environment{WSL: true})); !slices.Equal(got, []string{"apt"}) {
+		t.Errorf("WSL without systemd: got %v, want [apt]", got)
+	}
+	if got := names(removeUnusablePMs(slices.Clone(pms), environment{Systemd: true})); !slices.Equal(got, []string{"apt", "snap", "npm"}) {
+		t.Errorf("regular system: got %v, want all", got)
+	}
+}
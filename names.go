@@ -0,0 +1,121 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// embeddedNames maps canonical package names to the names used by each package manager
+//
+//go:embed names.json
+var embeddedNames []byte
+
+// localNamesFile in the config directory extends or overrides the embedded table
+const localNamesFile = "names.json"
+
+type nameTable struct {
+	Version  int                          `json:"version"`
+	Packages map[string]map[string]string `json:"packages"` // canonical name -> package manager -> name
+}
+
+var names nameTable
+
+// loadNames reads the embedded name table and merges the local one over it.
+func loadNames() {
+	if err := json.Unmarshal(embeddedNames, &names); err != nil {
+		panic("invalid embedded names.json: " + err.Error())
+	}
+
+	dir, err := configDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(dir, localNamesFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var local nameTable
+	if err := json.Unmarshal(data, &local); err != nil {
+		fmt.Printf("[warn] ignoring invalid names file %s: %v\n", path, err)
+		return
+	}
+	for canonical, perPM := range local.Packages {
+		if names.Packages[canonical] == nil {
+			names.Packages[canonical] = make(map[string]string)
+		}
+		maps.Copy(names.Packages[canonical], perPM)
+	}
+}
+
+// translateName returns the name of the package in pmName for a canonical name,
+// names that are not in the table are used as they are.
+func translateName(name, pmName string) string {
+	n := nameIn(name, pmName)
+	if n != name && !quiet {
+		fmt.Printf("[info] '%s' is called '%s' in %s\n", name, n, pmName)
+	}
+	return n
+}
+
+// nameIn is translateName without the message.
+func nameIn(name, pmName string) string {
+	if n, ok := names.Packages[name][pmName]; ok {
+		return n
+	}
+	return name
+}
+
+// printResolve shows the name of a package in every package manager.
+func printResolve(name string) {
+	perPM, ok := names.Packages[name]
+	if !ok {
+		// maybe it is the name used by some package managers
+		found := false
+		for _, canonical := range slices.Sorted(maps.Keys(names.Packages)) {
+			var pmNames []string
+			for pmName, n := range names.Packages[canonical] {
+				if n == name {
+					pmNames = append(pmNames, pmName)
+				}
+			}
+			if len(pmNames) > 0 {
+				slices.Sort(pmNames)
+				fmt.Printf("'%s' is the name of '%s' in %s, use 'i resolve %s' to see all names\n", name, canonical, strings.Join(pmNames, ", "), canonical)
+				found = true
+			}
+		}
+		if !found {
+			fmt.Printf("'%s' is not in the name table (v%d), it is used as it is by every package manager.\n", name, names.Version)
+		}
+		return
+	}
+
+	// the package managers with a different name, and the detected ones
+	pmNames := slices.Collect(maps.Keys(perPM))
+	for _, p := range detectedPMs {
+		if !slices.Contains(pmNames, p.Name) {
+			pmNames = append(pmNames, p.Name)
+		}
+	}
+	slices.Sort(pmNames)
+
+	fmt.Printf("names of '%s' (name table v%d):\n", name, names.Version)
+	for _, pmName := range pmNames {
+		n, ok := perPM[pmName]
+		if !ok {
+			n = name
+		}
+		line := fmt.Sprintf("  %-22s %s", pmName, n)
+		if slices.ContainsFunc(detectedPMs, func(p packageManager) bool { return p.Name == pmName }) {
+			line += "  (detected)"
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+)
+
+// osReleasePaths are read in order, see os-release(5)
+var osReleasePaths = []string{"/etc/os-release", "/usr/lib/os-release"}
+
+// osRelease holds the fields of os-release(5) that 'i' uses, all of them are optional.
+type osRelease struct {
+	ID        string
+	IDLike    []string // related distributions, closest first
+	VersionID string
+	VariantID string
+	Fields    map[string]string
+}
+
+func readOSRelease() (osRelease, error) {
+	var err error
+	for _, path := range osReleasePaths {
+		var f *os.File
+		f, err = os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		return parseOSRelease(f)
+	}
+	return osRelease{}, err
+}
+
+// parseOSRelease parses the KEY=value lines of os-release, values may be
+// quoted with double or single quotes and use backslash escapes.
+func parseOSRelease(r io.Reader) (osRelease, error) {
+	fields := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = unquoteOSReleaseValue(strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return osRelease{}, err
+	}
+
+	return osRelease{
+		ID:        strings.ToLower(fields["ID"]),
+		IDLike:    strings.Fields(strings.ToLower(fields["ID_LIKE"])),
+		VersionID: fields["VERSION_ID"],
+		VariantID: strings.ToLower(fields["VARIANT_ID"]),
+		Fields:    fields,
+	}, nil
+}
+
+// unquoteOSReleaseValue follows shell quoting: no escapes inside single quotes,
+// \" \\ \$ and \` are escapes inside double quotes and unquoted values, other
+// backslashes are kept (os-release(5)).
+func unquoteOSReleaseValue(value string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != '\'' && c == '\\' && i+1 < len(value) && strings.IndexByte("\"\\$`", value[i+1]) >= 0:
+			i++
+			b.WriteByte(value[i])
+		case quote == 0 && c == '#':
+			// trailing comment
+			return strings.TrimSpace(b.String())
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// systemPM returns the package manager of the distribution, trying ID first
+// and then every ID_LIKE entry, so unknown derivatives use their parent's manager.
+func (r osRelease) systemPM() string {
+	if p, ok := distro_pm[r.ID]; ok {
+		return p
+	}
+	for _, id := range r.IDLike {
+		if p, ok := distro_pm[id]; ok {
+			return p
+		}
+	}
+	return ""
+}
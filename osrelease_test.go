@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		fixture   string
+		id        string
+		idLike    []string
+		versionID string
+		variantID string
+		pm        string
+	}{
+		{"ubuntu", "ubuntu", []string{"debian"}, "24.04", "", "apt"},
+		{"debian", "debian", nil, "12", "", "apt"},
+		{"tuxedo", "tuxedo", []string{"ubuntu", "debian"}, "24.04", "", "apt"},
+		{"linuxmint", "linuxmint", []string{"ubuntu", "debian"}, "22", "", "apt"},
+		{"nobara", "nobara", []string{"rhel", "centos", "fedora"}, "40", "kde", "dnf"},
+		{"fedora-silverblue", "fedora", nil, "40", "silverblue", "dnf"},
+		{"rocky", "rocky", []string{"rhel", "centos", "fedora"}, "9.4", "", "dnf"},
+		{"arch", "arch", nil, "", "", "pacman"},
+		{"cachyos", "cachyos", []string{"arch"}, "", "", "pacman"},
+		{"opensuse-tumbleweed", "opensuse-tumbleweed", []string{"opensuse", "suse"}, "20240618", "", "zypper"},
+		{"sles", "sles", []string{"suse"}, "15.5", "", "zypper"},
+		{"alpine", "alpine", nil, "3.20.1", "", "apk"},
+		{"void", "void", nil, "", "", "xbps"},
+		{"nixos", "nixos", nil, "24.05", "", "nix-env"},
+		{"gentoo", "gentoo", nil, "2.15", "", "emerge"},
+		{"clearlinux", "clear-linux-os", []string{"clear-linux-os"}, "41780", "", "swupd"},
+		{"escapes", "weird", []string{"debian"}, "1.0", "server", "apt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "os-release", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			rel, err := parseOSRelease(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rel.ID != tt.id {
+				t.Errorf("ID: got %q, want %q", rel.ID, tt.id)
+			}
+			if !slices.Equal(rel.IDLike, tt.idLike) {
+				t.Errorf("ID_LIKE: got %q, want %q", rel.IDLike, tt.idLike)
+			}
+			if rel.VersionID != tt.versionID {
+				t.Errorf("VERSION_ID: got %q, want %q", rel.VersionID, tt.versionID)
+			}
+			if rel.VariantID != tt.variantID {
+				t.Errorf("VARIANT_ID: got %q, want %q", rel.VariantID, tt.variantID)
+			}
+			if got := rel.systemPM(); got != tt.pm {
+				t.Errorf("package manager: got %q, want %q", got, tt.pm)
+			}
+		})
+	}
+}
+
+func TestOSReleaseQuoting(t *testing.T) {
+	rel, err := parseOSRelease(strings.NewReader(`NAME="Escaped \"Quotes\" and \$dollar"
+PRETTY_NAME='Single \quotes keep backslashes'
+HOME_URL="a\nb \\ \x"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rel.Fields["NAME"], `Escaped "Quotes" and $dollar`; got != want {
+		t.Errorf("NAME: got %q, want %q", got, want)
+	}
+	if got, want := rel.Fields["PRETTY_NAME"], `Single \quotes keep backslashes`; got != want {
+		t.Errorf("PRETTY_NAME: got %q, want %q", got, want)
+	}
+	// only \" \\ \$ and \` are escapes
+	if got, want := rel.Fields["HOME_URL"], `a\nb \ \x`; got != want {
+		t.Errorf("HOME_URL: got %q, want %q", got, want)
+	}
+}
+
+func TestImmutableSystemPM(t *testing.T) {
+	// the host may be booted from ostree
+	saved := ostreeBootedPath
+	defer func() { ostreeBootedPath = saved }()
+	ostreeBootedPath = filepath.Join(t.TempDir(), "ostree-booted")
+
+	tests := []struct {
+		fixture string
+		pm      string
+	}{
+		{"fedora-silverblue", "rpm-ostree"},
+		{"opensuse-aeon", "transactional-update"},
+		{"nixos", "nix-env"},
+		{"ubuntu", ""},
+		{"arch", ""},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "os-release", tt.fixture))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel, err := parseOSRelease(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := immutableSystemPM(rel); got != tt.pm {
+			t.Errorf("%s: got %q, want %q", tt.fixture, got, tt.pm)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"os"
 	"regexp"
 	"strings"
 	"testing"
@@ -58,3 +59,46 @@ func BenchmarkStringReplacement(b *testing.B) {
 		generateCommandString(template, pkgName)
 	}
 }
+
+// resetDetection clears the globals that detectPM fills
+func resetDetection() {
+	pm = packageManager{}
+	detectedPMs = nil
+	immutableSystem = false
+	systemPMName = ""
+}
+
+// keepDetection restores the globals that detectPM fills when b ends
+func keepDetection(b *testing.B) {
+	savedPM, savedDetected, savedImmutable, savedSystemPM, savedOS := pm, detectedPMs, immutableSystem, systemPMName, operatingSystem
+	b.Cleanup(func() {
+		pm, detectedPMs, immutableSystem, systemPMName, operatingSystem = savedPM, savedDetected, savedImmutable, savedSystemPM, savedOS
+	})
+}
+
+// detection with an empty cache: os-release parsing and probing all executables
+func BenchmarkDetectPMCold(b *testing.B) {
+	b.Setenv("XDG_CACHE_HOME", b.TempDir())
+	keepDetection(b)
+	cachePath, err := detectionCachePath()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for b.Loop() {
+		os.Remove(cachePath)
+		resetDetection()
+		detectPM()
+	}
+}
+
+// detection with a valid cache, as in every run after the first one
+func BenchmarkDetectPMWarm(b *testing.B) {
+	b.Setenv("XDG_CACHE_HOME", b.TempDir())
+	keepDetection(b)
+	resetDetection()
+	detectPM() // fill the cache
+	for b.Loop() {
+		resetDetection()
+		detectPM()
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+)
+
+// platformsTable is the list of release targets, it is shared with
+// scripts/build-all.sh so the asset names always match the published binaries.
+//
+//go:embed scripts/platforms.txt
+var platformsTable string
+
+type platform struct {
+	OS    string
+	Arch  string
+	Asset string
+}
+
+// parsePlatforms reads "GOOS/GOARCH asset-name" lines, skipping comments and blank lines.
+func parsePlatforms(table string) []platform {
+	var platforms []platform
+	for line := range strings.SplitSeq(table, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(fields[0], "/")
+		if !ok {
+			continue
+		}
+		platforms = append(platforms, platform{OS: goos, Arch: goarch, Asset: fields[1]})
+	}
+	return platforms
+}
+
+// assetFor returns the release asset name built for the given GOOS/GOARCH.
+func assetFor(goos, goarch string) (string, error) {
+	for _, p := range parsePlatforms(platformsTable) {
+		if p.OS == goos && p.Arch == goarch {
+			return p.Asset, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported platform: %s/%s", goos, goarch)
+}
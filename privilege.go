@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// superUserEnv selects the privilege escalation tool, it overrides "sudo" in config.json
+const superUserEnv = "I_SUDO"
+
+// escalationTools are tried in this order when no tool is chosen
+var escalationTools = []string{"sudo", "doas", "run0", "pkexec", "su"}
+
+var errNoSuperUser = errors.New("permission denied and no sudo/doas/run0/pkexec/su found")
+
+// escalator runs commands as super user, an empty Name runs them directly (already root).
+type escalator struct {
+	Name string
+	Path string
+}
+
+var (
+	superUser         escalator
+	superUserErr      error
+	superUserResolved bool
+)
+
+// findEscalator picks the escalation tool once: none when running as root,
+// else the one from I_SUDO or config, else the first one found.
+func findEscalator() (escalator, error) {
+	if superUserResolved {
+		return superUser, superUserErr
+	}
+	superUserResolved = true
+	superUser, superUserErr = resolveEscalator()
+
+	if superUserErr == nil && !quiet {
+		if superUser.Name == "" {
+			fmt.Println("[info] running as root, no privilege escalation needed")
+		} else {
+			fmt.Printf("[info] using '%s' to run commands as super user\n", superUser.Name)
+		}
+	}
+	return superUser, superUserErr
+}
+
+func resolveEscalator() (escalator, error) {
+	choice := os.Getenv(superUserEnv)
+	source := superUserEnv
+	if choice == "" {
+		choice = cfg.Sudo
+		source = "config"
+	}
+
+	if choice == "none" || (choice == "" && os.Geteuid() == 0) {
+		return escalator{}, nil
+	}
+
+	// Termux apps run as a normal Android user without sudo, its pkg needs no root
+	if choice == "" && runningEnv.Termux {
+		return escalator{}, nil
+	}
+
+	if choice != "" {
+		if !slices.Contains(escalationTools, choice) {
+			return escalator{}, fmt.Errorf("unsupported privilege escalation tool '%s' in %s, use one of: %s, none", choice, source, strings.Join(escalationTools, ", "))
+		}
+		path, err := exec.LookPath(choice)
+		if err != nil {
+			return escalator{}, fmt.Errorf("privilege escalation tool '%s' from %s not found", choice, source)
+		}
+		return escalator{Name: choice, Path: path}, nil
+	}
+
+	for _, name := range escalationTools {
+		if path, err := exec.LookPath(name); err == nil {
+			return escalator{Name: name, Path: path}, nil
+		}
+	}
+	if runningEnv.Container != "" {
+		return escalator{}, fmt.Errorf("%w, inside a %s container run 'i' as root instead", errNoSuperUser, runningEnv.Container)
+	}
+	return escalator{}, errNoSuperUser
+}
+
+// command wraps args to run them as super user.
+func (e escalator) command(args ...string) *exec.Cmd {
+	switch e.Name {
+	case "":
+		return exec.Command(args[0], args[1:]...)
+	case "su":
+		// su takes the whole command as one shell string
+		return exec.Command(e.Path, "root", "-c", shellJoin(args))
+	default:
+		return exec.Command(e.Path, args...)
+	}
+}
+
+// shellJoin quotes every argument for sh.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func runAsSuperUser(args ...string) error {
+	e, err := findEscalator()
+	if err != nil {
+		return err
+	}
+	cmd := e.command(args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// needsSuperUser reports whether any of the command templates runs with sudo.
+func needsSuperUser(templates ...string) bool {
+	for _, t := range templates {
+		if strings.HasPrefix(t, "sudo ") {
+			return true
+		}
+	}
+	return false
+}
+
+// preauthenticate asks for the password once before a batch of super user
+// commands and keeps the credentials fresh until stop is called. It fails
+// fast when escalation is impossible, e.g. a password is needed but there is no terminal.
+func preauthenticate() (stop func(), err error) {
+	noop := func() {}
+
+	e, err := findEscalator()
+	if err != nil {
+		return noop, err
+	}
+
+	interactive := isTerminal(os.Stdin)
+
+	switch e.Name {
+	case "":
+		return noop, nil
+	case "sudo", "doas":
+		validate := []string{"-v"}
+		if e.Name == "doas" {
+			// doas has no -v, running true caches the credentials if 'persist' is set in doas.conf
+			validate = []string{"true"}
+		}
+
+		if !interactive {
+			if err := exec.Command(e.Path, append([]string{"-n"}, validate...)...).Run(); err != nil {
+				return noop, fmt.Errorf("'%s' needs a password but there is no terminal to ask for it, run '%s %s' first or allow passwordless use", e.Name, e.Name, strings.Join(validate, " "))
+			}
+		} else {
+			if !quiet {
+				fmt.Printf("[info] authenticating with '%s' once for all commands\n", e.Name)
+			}
+			cmd := exec.Command(e.Path, validate...)
+			cmd.Stdin = os.Stdin
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				return noop, fmt.Errorf("'%s' authentication failed: %w", e.Name, err)
+			}
+		}
+
+		if e.Name == "doas" {
+			return noop, nil
+		}
+
+		// refresh the sudo timestamp until the batch is done
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					exec.Command(e.Path, "-n", "-v").Run()
+				}
+			}
+		}()
+		return func() { close(done) }, nil
+	default:
+		// run0, pkexec and su can not keep credentials between commands
+		if e.Name == "su" && !interactive {
+			return noop, fmt.Errorf("'su' asks for the root password for every command but there is no terminal to ask for it")
+		}
+		if !quiet {
+			fmt.Printf("[warn] '%s' can not cache credentials, you may be asked for the password for each package manager\n", e.Name)
+		}
+		return noop, nil
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const changelogURL = "https://raw.githubusercontent.com/abanoubha/i/main/CHANGELOG.md"
+
+// printReleaseNotes shows what changed in the new release, from the release
+// body or, if it is empty, from the CHANGELOG.md sections newer than the installed version.
+func printReleaseNotes(release Release, newVersion string) {
+	title := release.Name
+	if title == "" {
+		title = release.TagName
+	}
+	fmt.Printf("\n[info] release %s", title)
+	if !release.PublishedAt.IsZero() {
+		fmt.Printf(" (published %s)", release.PublishedAt.Format(time.DateOnly))
+	}
+	fmt.Println()
+
+	notes := strings.TrimSpace(release.Body)
+	if notes == "" {
+		notes = changelogNotes(version, newVersion)
+	}
+	if notes == "" {
+		fmt.Println("[info] no release notes found")
+		fmt.Println()
+		return
+	}
+	fmt.Printf("\n%s\n\n", notes)
+}
+
+// changelogNotes fetches CHANGELOG.md and returns the sections after installed up to latest.
+func changelogNotes(installed, latest string) string {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(changelogURL)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ""
+	}
+	return changelogSections(string(data), installed, latest)
+}
+
+// changelogSections returns the "## v..." sections of changelog with a
+// release number greater than installed and not greater than latest.
+func changelogSections(changelog, installed, latest string) string {
+	from, err := strconv.Atoi(releaseNumber(installed))
+	if err != nil {
+		return ""
+	}
+	to, err := strconv.Atoi(releaseNumber(latest))
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	include := false
+	for line := range strings.SplitSeq(changelog, "\n") {
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			// old headings are dotted (v25.12.18), new ones are not (v260201)
+			n, err := strconv.Atoi(strings.ReplaceAll(strings.TrimPrefix(heading, "v"), ".", ""))
+			include = err == nil && n > from && n <= to
+		}
+		if include {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+)
+
+// routingRule chooses the package managers to install matching packages with,
+// rules are read from "rules" in config.json and evaluated in order.
+type routingRule struct {
+	Match string   `json:"match"` // glob for the package name, e.g. "node*", empty matches every package
+	GUI   bool     `json:"gui"`   // only desktop apps, i.e. packages with a flatpak application that uses a display
+	Use   string   `json:"use"`   // install with this package manager
+	Avoid []string `json:"avoid"` // never install with these package managers
+}
+
+// route is the result of the rules for one package.
+type route struct {
+	Use   string   // from the first matching rule with "use"
+	Avoid []string // from every matching rule
+	AppID string   // the flatpak application ID, if a "gui" rule looked it up
+}
+
+// routeFor evaluates the config rules for pkg, a forced package manager
+// (e.g. --apt) overrides them.
+func routeFor(pkg string) route {
+	var r route
+	if forcedPM != "" {
+		return r
+	}
+
+	guiChecked, gui := false, false
+	for _, rule := range cfg.Rules {
+		if rule.Match != "" {
+			if ok, err := path.Match(rule.Match, pkg); err != nil || !ok {
+				continue
+			}
+		}
+		if rule.GUI {
+			if !guiChecked {
+				guiChecked = true
+				if slices.ContainsFunc(detectedPMs, func(p packageManager) bool { return p.Name == "flatpak" }) {
+					r.AppID, gui = findGUIFlatpakApp(pkg)
+				}
+			}
+			if !gui {
+				continue
+			}
+		}
+
+		if r.Use == "" && rule.Use != "" {
+			if !slices.ContainsFunc(detectedPMs, func(p packageManager) bool { return p.Name == rule.Use }) {
+				fmt.Printf("[warn] a rule wants to install '%s' with %s, which is not detected\n", pkg, rule.Use)
+			} else {
+				r.Use = rule.Use
+				if !quiet {
+					fmt.Printf("[info] '%s' matches a rule, using %s\n", pkg, r.Use)
+				}
+			}
+		}
+		r.Avoid = append(r.Avoid, rule.Avoid...)
+	}
+
+	if slices.Contains(r.Avoid, r.Use) {
+		fmt.Printf("[warn] the rules for '%s' both use and avoid %s, avoiding it\n", pkg, r.Use)
+		r.Use = ""
+	}
+	return r
+}
+
+// candidates returns the package managers to try in order: the one chosen by
+// the rules, then the primary one, then the other detected ones, without the avoided ones.
+func (r route) candidates() []packageManager {
+	if forcedPM != "" {
+		return []packageManager{pm}
+	}
+
+	var pms []packageManager
+	if r.Use != "" {
+		i := slices.IndexFunc(detectedPMs, func(p packageManager) bool { return p.Name == r.Use })
+		pms = append(pms, detectedPMs[i])
+	}
+	for _, p := range append([]packageManager{pm}, detectedPMs...) {
+		if slices.Contains(r.Avoid, p.Name) || slices.ContainsFunc(pms, func(c packageManager) bool { return c.Name == p.Name }) {
+			continue
+		}
+		pms = append(pms, p)
+	}
+	return pms
+}
+
+// nameFor is the package name to install with pmName, flatpak uses the application ID if one was found.
+func (r route) nameFor(pkg, pmName string) string {
+	if pmName == "flatpak" && r.AppID != "" {
+		return r.AppID
+	}
+	return translateName(pkg, pmName)
+}
+
+// ruleOverrides reports whether a rule matching pkg by name uses a package
+// manager or avoids pmName, the rules win over the package manager a name selects.
+func ruleOverrides(pkg, pmName string) bool {
+	return slices.ContainsFunc(cfg.Rules, func(rule routingRule) bool {
+		if rule.GUI {
+			return false
+		}
+		if ok, err := path.Match(rule.Match, pkg); rule.Match != "" && (err != nil || !ok) {
+			return false
+		}
+		return rule.Use != "" || slices.Contains(rule.Avoid, pmName)
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runMise runs the mise commands 'i' implements: upgrade updates the plugins,
+// then upgrades the runtimes within the versions they were installed with (node@20).
+func runMise(action string) error {
+	if action != "upgrade" {
+		return fmt.Errorf("unknown mise command: %s", action)
+	}
+	if err := runCommand("mise plugins update", ""); err != nil {
+		fmt.Printf("[warn] updating the mise plugins: %v\n", err)
+	}
+	return runCommand("mise upgrade", "")
+}
+
+// runAsdf runs the asdf commands 'i' implements for name@version: install
+// adds the plugin, installs the latest version matching version and makes it
+// the global default; uninstall removes a version, or the plugin with all of
+// them after asking.
+func runAsdf(action, arg string) error {
+	name, prefix, _ := strings.Cut(arg, "@")
+	switch action {
+	case "install":
+		if !hasLine(commandOutput("asdf plugin list", ""), name) {
+			if err := runCommand("asdf plugin add x", name); err != nil {
+				return err
+			}
+		}
+		version := commandOutput("asdf latest x", strings.TrimSpace(name+" "+prefix))
+		if version == "" {
+			return fmt.Errorf("no version of %s matches '%s'", name, prefix)
+		}
+		if err := runCommand("asdf install x", name+" "+version); err != nil {
+			return err
+		}
+		// asdf 0.16 replaced 'global' with 'set --home'
+		if commandSucceeds("asdf set --home x", name+" "+version) {
+			return nil
+		}
+		return runCommand("asdf global x", name+" "+version)
+	case "uninstall":
+		if prefix == "" {
+			// removing the plugin deletes every installed version of it
+			versions := asdfVersions(commandOutput("asdf list x", name))
+			if len(versions) > 0 {
+				fmt.Printf("[info] this removes %s %s\n", name, strings.Join(versions, ", "))
+			}
+			if !askConfirmation(fmt.Sprintf("Remove the asdf plugin %s with all its versions?", name)) {
+				return fmt.Errorf("canceled, uninstall one version with %s@<version>", name)
+			}
+			return runCommand("asdf plugin remove x", name)
+		}
+		return runCommand("asdf uninstall x", name+" "+prefix)
+	}
+	return fmt.Errorf("unknown asdf command: %s", action)
+}
+
+// asdfVersions parses the output of 'asdf list name', one version per line,
+// the current one marked with '*'.
+func asdfVersions(out string) []string {
+	var versions []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if v := strings.TrimPrefix(strings.TrimSpace(line), "*"); v != "" && !strings.HasPrefix(v, "No versions") {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// hasLine reports whether a line of out is s, ignoring the spaces around it.
+func hasLine(out, s string) bool {
+	for line := range strings.SplitSeq(out, "\n") {
+		if strings.TrimSpace(line) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// runSdkman runs sdk with the arguments for name@version, sdk is a shell
+// function defined by the init script of SDKMAN, it is sourced first.
+func runSdkman(action, arg string) error {
+	args := []string{action}
+	if arg != "" {
+		name, version, _ := strings.Cut(arg, "@")
+		args = append(args, name)
+		if version != "" {
+			args = append(args, version)
+		}
+	}
+	switch {
+	case action == "uninstall" && len(args) < 3:
+		return fmt.Errorf("sdkman uninstalls one version at a time, e.g. %s@21.0.5-tem", arg)
+	case action == "upgrade" && arg == "":
+		// refresh the list of candidates and their versions first
+		if err := sdk("update"); err != nil {
+			fmt.Printf("[warn] updating the sdkman candidates: %v\n", err)
+		}
+	}
+	return sdk(args...)
+}
+
+func sdk(args ...string) error {
+	ok, script := pm_commands["sdkman"].probe()
+	if !ok {
+		return fmt.Errorf("sdkman is not installed, see https://sdkman.io/install")
+	}
+	if !quiet {
+		fmt.Printf("[info] executing: sdk %s\n", strings.Join(args, " "))
+	}
+	cmd := exec.Command("bash", append([]string{"-c", `source "$0" && sdk "$@"`, script}, args...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error executing command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// readState decodes the JSON file name from the state directory into v,
+// a missing file leaves v unchanged.
+func readState(name string, v any) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeState encodes v as JSON into the file name in the state directory.
+func writeState(name string, v any) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// installedWithFile records which package manager installed each package through 'i'
+const installedWithFile = "installed.json"
+
+// recordInstall remembers that pkg was installed with pmName.
+func recordInstall(pkg, pmName string) {
+	records := map[string]string{}
+	readState(installedWithFile, &records)
+	records[pkg] = pmName
+	writeState(installedWithFile, records)
+}
+
+// forgetInstall removes the record of pkg after it is uninstalled.
+func forgetInstall(pkg string) {
+	records := map[string]string{}
+	readState(installedWithFile, &records)
+	if _, ok := records[pkg]; !ok {
+		return
+	}
+	delete(records, pkg)
+	writeState(installedWithFile, records)
+}
+
+// installedWith returns the package manager that installed pkg through 'i', if recorded.
+func installedWith(pkg string) string {
+	records := map[string]string{}
+	readState(installedWithFile, &records)
+	return records[pkg]
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// runCargo runs the cargo commands 'i' implements: upgrade reinstalls every
+// installed crate, cargo skips the ones that are up to date.
+func runCargo(action string) error {
+	if action != "upgrade" {
+		return fmt.Errorf("unknown cargo command: %s", action)
+	}
+	out, err := exec.Command("cargo", "install", "--list").Output()
+	if err != nil {
+		return fmt.Errorf("cargo install --list: %w", err)
+	}
+	var failed []string
+	for _, crate := range parseCargoList(string(out)) {
+		if err := runCommand("cargo install x", crate); err != nil {
+			failed = append(failed, crate)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not upgrade %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// parseCargoList reads the crate names of 'cargo install --list', lines like
+// "ripgrep v14.1.1:" followed by the indented binaries.
+func parseCargoList(out string) []string {
+	var crates []string
+	for line := range strings.SplitSeq(out, "\n") {
+		if line == "" || strings.HasPrefix(line, " ") {
+			continue
+		}
+		if name, _, ok := strings.Cut(line, " "); ok {
+			crates = append(crates, name)
+		}
+	}
+	return crates
+}
+
+// goBinDir is where 'go install' puts binaries: $GOBIN, or the bin directory of the first GOPATH entry.
+func goBinDir() (string, error) {
+	out, err := exec.Command("go", "env", "GOBIN", "GOPATH").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
+		return strings.TrimSpace(lines[0]), nil
+	}
+	if len(lines) < 2 {
+		return "", fmt.Errorf("GOPATH is not set")
+	}
+	return filepath.Join(filepath.SplitList(strings.TrimSpace(lines[1]))[0], "bin"), nil
+}
+
+// goBinary is a binary installed with 'go install'.
+type goBinary struct {
+	File    string
+	Path    string // the package path it was built from
+	Version string // of its module
+}
+
+// goBinaries reads the build information of the binaries in the go bin directory.
+func goBinaries() ([]goBinary, error) {
+	dir, err := goBinDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var binaries []goBinary
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		file := filepath.Join(dir, e.Name())
+		out, err := exec.Command("go", "version", "-m", file).Output()
+		if err != nil {
+			continue // not a Go binary
+		}
+		if b := parseGoVersion(string(out)); b.Path != "" {
+			b.File = file
+			binaries = append(binaries, b)
+		}
+	}
+	return binaries, nil
+}
+
+// parseGoVersion reads the package path and module version of 'go version -m', e.g.
+//
+//	/home/user/go/bin/gopls: go1.23.2
+//		path	golang.org/x/tools/gopls
+//		mod	golang.org/x/tools/gopls	v0.16.2	h1:...
+func parseGoVersion(out string) goBinary {
+	var b goBinary
+	for line := range strings.SplitSeq(out, "\n") {
+		f := strings.Fields(line)
+		switch {
+		case len(f) >= 2 && f[0] == "path":
+			b.Path = f[1]
+		case len(f) >= 3 && f[0] == "mod":
+			b.Version = f[2]
+		}
+	}
+	return b
+}
+
+// runGo runs the go commands 'i' implements: install (at the latest version
+// unless one is given), uninstall, upgrade of every installed binary and list.
+func runGo(action, pkg string) error {
+	switch action {
+	case "install":
+		if !strings.Contains(pkg, "@") {
+			pkg += "@latest"
+		}
+		return runCommand("go install x", pkg)
+	case "uninstall":
+		binaries, err := goBinaries()
+		if err != nil {
+			return err
+		}
+		i := slices.IndexFunc(binaries, func(b goBinary) bool { return b.Path == pkg })
+		if i < 0 {
+			return fmt.Errorf("no binary built from %s found", pkg)
+		}
+		if !quiet {
+			fmt.Printf("[info] removing %s\n", binaries[i].File)
+		}
+		return os.Remove(binaries[i].File)
+	case "upgrade":
+		binaries, err := goBinaries()
+		if err != nil {
+			return err
+		}
+		var failed []string
+		for _, b := range binaries {
+			if err := runCommand("go install x", b.Path+"@latest"); err != nil {
+				failed = append(failed, b.Path)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("could not upgrade %s", strings.Join(failed, ", "))
+		}
+		return nil
+	case "list":
+		binaries, err := goBinaries()
+		if err != nil {
+			return err
+		}
+		for _, b := range binaries {
+			fmt.Printf("%s %s (%s)\n", b.Path, b.Version, b.File)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown go command: %s", action)
+}
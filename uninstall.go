@@ -1,24 +1,27 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 )
 
 func selfUninstall() {
-	installName := os.Getenv("INSTALL_NAME")
-	if installName == "" {
-		installName = "i"
-	}
+	installName := installName()
 	installDir := os.Getenv("INSTALL_DIR")
 
 	var target string
 
-	if installDir != "" {
+	if userMode {
+		userDir, err := userBinDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot find the user bin directory: %v\n", err)
+			os.Exit(1)
+		}
+		target = filepath.Join(userDir, installName)
+	} else if installDir != "" {
 		target = filepath.Join(installDir, installName)
 	} else {
 		foundPath, err := exec.LookPath(installName)
@@ -26,6 +29,9 @@ func selfUninstall() {
 			target = foundPath
 		} else {
 			fallbacks := []string{"/usr/local/bin", "/usr/bin"}
+			if userDir, err := userBinDir(); err == nil {
+				fallbacks = append(fallbacks, userDir)
+			}
 			for _, dir := range fallbacks {
 				path := filepath.Join(dir, installName)
 				if info, err := os.Stat(path); err == nil && !info.IsDir() {
@@ -48,26 +54,22 @@ func selfUninstall() {
 	}
 
 	fmt.Printf("This will remove:\n  %s\n", target)
-	fmt.Print("Proceed? [y/N] ")
-
-	reader := bufio.NewReader(os.Stdin)
-	ans, _ := reader.ReadString('\n')
-	ans = strings.ToLower(strings.TrimSpace(ans))
-
-	if ans != "y" && ans != "yes" {
+	if !askConfirmation("Proceed?") {
 		fmt.Println("Aborted.")
 		os.Exit(1)
 	}
 
 	err := os.Remove(target)
 	if err != nil {
-		if os.IsPermission(err) {
+		if os.IsPermission(err) && !userMode {
 			if err := runAsSuperUser("rm", "-f", target); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to remove %s (even with sudo/doas).\n", target)
+				if errors.Is(err, errNoSuperUser) {
+					fmt.Fprintf(os.Stderr, "Cannot remove %s: %v.\n", target, err)
+				} else {
+					fmt.Fprintf(os.Stderr, "Failed to remove %s (even as super user): %v\n", target, err)
+				}
 				os.Exit(1)
 			}
-			fmt.Fprintf(os.Stderr, "Cannot remove %s: permission denied and sudo/doas not found.\n", target)
-			os.Exit(1)
 		} else {
 			fmt.Fprintf(os.Stderr, "Error removing file: %v\n", err)
 			os.Exit(1)
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// set I_NO_UPDATE_CHECK=1 to disable the update check (e.g. in CI)
+	noUpdateCheckEnv           = "I_NO_UPDATE_CHECK"
+	defaultUpdateCheckInterval = 24 * time.Hour
+	updateCheckAction          = "__update-check" // hidden sub-command run in the background
+)
+
+type updateCheckCache struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest_version"`
+}
+
+func updateCheckInterval() time.Duration {
+	if cfg.UpdateCheckInterval == "" {
+		return defaultUpdateCheckInterval
+	}
+	d, err := time.ParseDuration(cfg.UpdateCheckInterval)
+	if err != nil {
+		return defaultUpdateCheckInterval
+	}
+	return d
+}
+
+// updateCheckEnabled reports whether to check for (and tell about) new releases for this run.
+func updateCheckEnabled(action string) bool {
+	if quiet || os.Getenv(noUpdateCheckEnv) != "" || os.Getenv("CI") != "" {
+		return false
+	}
+	if updateCheckInterval() <= 0 {
+		return false
+	}
+	switch action {
+	case "selfup", "selfupdate", "selfupgrade", "selfun", "selfuninstall", "selfdelete", "version", "help":
+		return false
+	}
+	return isTerminal(os.Stdout) && isTerminal(os.Stderr)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func updateCheckCachePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "update-check.json"), nil
+}
+
+func readUpdateCheckCache() (updateCheckCache, error) {
+	var c updateCheckCache
+	path, err := updateCheckCachePath()
+	if err != nil {
+		return c, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+func writeUpdateCheckCache(c updateCheckCache) error {
+	path, err := updateCheckCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// scheduleUpdateCheck starts a detached 'i __update-check' process when the
+// cached result is older than the interval, so the user's command never waits for the network.
+func scheduleUpdateCheck() {
+	c, _ := readUpdateCheckCache()
+	if time.Since(c.CheckedAt) < updateCheckInterval() {
+		return
+	}
+
+	// mark the check as done before it runs, so parallel runs do not start more checks
+	c.CheckedAt = time.Now()
+	if err := writeUpdateCheckCache(c); err != nil {
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(exe, updateCheckAction)
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	cmd.Process.Release()
+}
+
+// runUpdateCheck queries the latest release and caches its version.
+func runUpdateCheck() {
+	assetName, err := detectAsset()
+	if err != nil {
+		return
+	}
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", GithubRepo)
+	_, latest, err := getDownloadURL(apiURL, assetName)
+	if err != nil {
+		return
+	}
+	// latest is the asset suffix, e.g. "260210.exe" on Windows
+	writeUpdateCheckCache(updateCheckCache{CheckedAt: time.Now(), Latest: releaseNumber(latest)})
+}
+
+// printUpdateNotice prints one line if the last check found a newer release.
+func printUpdateNotice() {
+	c, err := readUpdateCheckCache()
+	if err != nil {
+		return
+	}
+	if latest, ok := updateAvailable(c.Latest, version); ok {
+		fmt.Fprintf(os.Stderr, "\n[info] a new version of 'i' is available: v%s -> v%s, run 'i selfup' to upgrade\n", version, latest)
+	}
+}
+
+// updateAvailable returns the release number of the cached latest version
+// (older caches kept the asset suffix, e.g. "260210.exe") if it is newer than current.
+func updateAvailable(latest, current string) (string, bool) {
+	latest = releaseNumber(latest)
+	return latest, isNewerVersion(latest, current)
+}
+
+// releaseNumber extracts the date-based release number, e.g. "v260203.exe" -> "260203"
+func releaseNumber(s string) string {
+	s = strings.TrimPrefix(s, "v")
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
+
+// isNewerVersion compares release tags by their dot-separated numbers (v260203,
+// 1.10 > 1.9). A pre-release (260210-rc1) is never offered, but is older than its release.
+func isNewerVersion(latest, current string) bool {
+	l, lPre, ok := parseVersion(latest)
+	if !ok || lPre {
+		return false
+	}
+	c, cPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range max(len(l), len(c)) {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return cPre
+}
+
+// parseVersion splits a tag like "v1.2.3-rc1" into its numbers and whether it is a pre-release.
+func parseVersion(s string) ([]int, bool, bool) {
+	core, pre, _ := strings.Cut(strings.TrimPrefix(s, "v"), "-")
+	var nums []int
+	for part := range strings.SplitSeq(core, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false, false
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre != "", true
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// validVersion allows the characters versions of all package managers use,
+// e.g. 20, 1.2.3-1ubuntu2, 1:2.3+dfsg, 3.12~rc1
+var validVersion = regexp.MustCompile(`^[a-zA-Z0-9_.+~:-]+$`)
+
+// splitVersion separates a version from a package name written as
+// name@version (node@20) or name=version (curl=7.88.1-10), version is "" if there is none.
+func splitVersion(pkg string) (name, version string) {
+	i := strings.LastIndexAny(pkg, "@=")
+	if i <= 0 || i == len(pkg)-1 {
+		return pkg, ""
+	}
+	return pkg[:i], pkg[i+1:]
+}
+
+// pinnedName returns the argument that makes c install version of name,
+// or an error if c can not install a specific version.
+func pinnedName(c commands, name, version string) (string, error) {
+	if version == "" {
+		return name, nil
+	}
+	if c.VersionFormat == "" {
+		return "", fmt.Errorf("%s can not install a specific version of a package", c.Name)
+	}
+	return fmt.Sprintf(c.VersionFormat, name, version), nil
+}
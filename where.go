@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// unknownVersion is shown when a package manager can not be asked for a version
+const unknownVersion = "?"
+
+// pkgVersions is what a package manager knows about a package, "" means none.
+type pkgVersions struct {
+	Available string // the version in the repositories
+	Installed string // the installed version
+}
+
+// versionQueries ask a package manager for the versions of a package,
+// package managers without one are listed as unknown by 'i where'.
+var versionQueries = map[string]func(pkg string) pkgVersions{
+	"apt": func(pkg string) pkgVersions {
+		return parseAptPolicy(commandOutput("apt-cache policy x", pkg))
+	},
+	"brew": func(pkg string) pkgVersions {
+		return parseBrewInfo(commandOutput("brew info --json=v2 x", pkg))
+	},
+	"port": func(pkg string) pkgVersions {
+		v := pkgVersions{Available: keyValue(commandOutput("port info --version x", pkg), "version")}
+		for line := range strings.SplitSeq(commandOutput("port installed x", pkg), "\n") {
+			// "  fd @8.7.0_0 (active)"
+			if f := strings.Fields(line); len(f) >= 2 && f[0] == pkg && strings.HasPrefix(f[1], "@") {
+				v.Installed = strings.TrimPrefix(f[1], "@")
+			}
+		}
+		return v
+	},
+	"flatpak": func(pkg string) pkgVersions {
+		return pkgVersions{
+			Available: matchingColumn(commandOutput("flatpak search --columns=application,version x", pkg), pkg),
+			Installed: matchingColumn(commandOutput("flatpak list --app --columns=application,version", ""), pkg),
+		}
+	},
+	"snap": func(pkg string) pkgVersions {
+		return parseSnapInfo(commandOutput("snap info x", pkg))
+	},
+	"dnf": func(pkg string) pkgVersions {
+		return parseDnfInfo(commandOutput("dnf info -q x", pkg))
+	},
+	"yum": func(pkg string) pkgVersions {
+		return parseDnfInfo(commandOutput("yum info -q x", pkg))
+	},
+	"rpm": func(pkg string) pkgVersions {
+		return pkgVersions{Available: unknownVersion, Installed: rpmVersion(pkg)}
+	},
+	"rpm-ostree": func(pkg string) pkgVersions {
+		return pkgVersions{Available: unknownVersion, Installed: rpmVersion(pkg)}
+	},
+	"zypper": func(pkg string) pkgVersions {
+		return pkgVersions{
+			Available: keyValue(commandOutput("zypper -q info x", pkg), "Version"),
+			Installed: rpmVersion(pkg),
+		}
+	},
+	"transactional-update": func(pkg string) pkgVersions {
+		return pkgVersions{
+			Available: keyValue(commandOutput("zypper -q info x", pkg), "Version"),
+			Installed: rpmVersion(pkg),
+		}
+	},
+	"pacman": func(pkg string) pkgVersions {
+		v := pkgVersions{Available: keyValue(commandOutput("pacman -Si x", pkg), "Version")}
+		// "fd 10.2.0-1"
+		if f := strings.Fields(commandOutput("pacman -Q x", pkg)); len(f) == 2 {
+			v.Installed = f[1]
+		}
+		return v
+	},
+	"apk": func(pkg string) pkgVersions {
+		return parseApkList(commandOutput("apk list x", pkg), pkg)
+	},
+	"xbps": func(pkg string) pkgVersions {
+		// "fd-10.2.0_1"
+		return pkgVersions{
+			Available: strings.TrimPrefix(commandOutput("xbps-query -R -p pkgver x", pkg), pkg+"-"),
+			Installed: strings.TrimPrefix(commandOutput("xbps-query -p pkgver x", pkg), pkg+"-"),
+		}
+	},
+	"nix-env": func(pkg string) pkgVersions {
+		return pkgVersions{
+			Available: nixVersion(commandOutput("nix-env -qa --json -A nixpkgs.x", pkg)),
+			Installed: nixVersion(commandOutput("nix-env -q --json x", pkg)),
+		}
+	},
+	"pkg": func(pkg string) pkgVersions {
+		if runningEnv.Termux {
+			// Termux pkg wraps apt
+			return parseAptPolicy(commandOutput("apt-cache policy x", pkg))
+		}
+		return pkgVersions{
+			Available: commandOutput("pkg rquery %v x", pkg),
+			Installed: commandOutput("pkg query %v x", pkg),
+		}
+	},
+	"winget": func(pkg string) pkgVersions {
+		return pkgVersions{
+			Available: keyValue(commandOutput("winget show --exact x", pkg), "Version"),
+			Installed: unknownVersion,
+		}
+	},
+	"appimage": func(pkg string) pkgVersions {
+		return pkgVersions{Available: unknownVersion, Installed: readAppImageRegistry()[pkg].Version}
+	},
+	"github": func(pkg string) pkgVersions {
+		v := pkgVersions{Installed: readGithubRegistry()[pkg].Version}
+		if release, err := latestRelease(pkg); err == nil {
+			v.Available = release.TagName
+		}
+		return v
+	},
+	"choco": func(pkg string) pkgVersions {
+		// --limit-output prints "name|version"
+		_, available, _ := strings.Cut(commandOutput("choco search --exact --limit-output x", pkg), "|")
+		_, installed, _ := strings.Cut(commandOutput("choco list --exact --limit-output x", pkg), "|")
+		return pkgVersions{Available: available, Installed: installed}
+	},
+}
+
+// commandOutput runs a read-only command template silently and returns its
+// trimmed standard output, or "" if it fails (most managers exit with an
+// error for unknown or not installed packages).
+func commandOutput(template string, pkgName string) string {
+	parts := strings.Fields(strings.TrimPrefix(expandTemplate(template, pkgName), "sudo "))
+	if len(parts) == 0 {
+		return ""
+	}
+	out, err := queryOutput(parts[0], parts[1:]...)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// queryOutput runs a command for commandOutput, the tests replace it.
+var queryOutput = func(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	// the output is parsed, keep it untranslated
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	return cmd.Output()
+}
+
+// keyValue returns the value of the first "key: value" line of out.
+func keyValue(out, key string) string {
+	for line := range strings.SplitSeq(out, "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// matchingColumn returns the second column of the tab separated line whose
+// first column is the application ID pkg or ends with ".pkg".
+func matchingColumn(out, pkg string) string {
+	pkg = strings.ToLower(pkg)
+	for line := range strings.SplitSeq(out, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		id := strings.ToLower(strings.TrimSpace(fields[0]))
+		if id == pkg || strings.HasSuffix(id, "."+pkg) {
+			return strings.TrimSpace(fields[1])
+		}
+	}
+	return ""
+}
+
+func rpmVersion(pkg string) string {
+	return commandOutput("rpm -q --qf %{VERSION}-%{RELEASE} x", pkg)
+}
+
+// parseAptPolicy reads the Installed and Candidate lines of 'apt-cache policy'.
+func parseAptPolicy(out string) pkgVersions {
+	v := pkgVersions{
+		Available: keyValue(out, "Candidate"),
+		Installed: keyValue(out, "Installed"),
+	}
+	if v.Available == "(none)" {
+		v.Available = ""
+	}
+	if v.Installed == "(none)" {
+		v.Installed = ""
+	}
+	return v
+}
+
+// parseBrewInfo reads the JSON of 'brew info --json=v2', formulae first, then casks.
+func parseBrewInfo(out string) pkgVersions {
+	var info struct {
+		Formulae []struct {
+			Versions struct {
+				Stable string `json:"stable"`
+			} `json:"versions"`
+			Installed []struct {
+				Version string `json:"version"`
+			} `json:"installed"`
+		} `json:"formulae"`
+		Casks []struct {
+			Version   string `json:"version"`
+			Installed string `json:"installed"`
+		} `json:"casks"`
+	}
+	if json.Unmarshal([]byte(out), &info) != nil {
+		return pkgVersions{}
+	}
+	if len(info.Formulae) > 0 {
+		f := info.Formulae[0]
+		v := pkgVersions{Available: f.Versions.Stable}
+		if len(f.Installed) > 0 {
+			v.Installed = f.Installed[len(f.Installed)-1].Version
+		}
+		return v
+	}
+	if len(info.Casks) > 0 {
+		return pkgVersions{Available: info.Casks[0].Version, Installed: info.Casks[0].Installed}
+	}
+	return pkgVersions{}
+}
+
+// parseSnapInfo reads the installed line and the first open channel of 'snap info'.
+func parseSnapInfo(out string) pkgVersions {
+	var v pkgVersions
+	inChannels := false
+	for line := range strings.SplitSeq(out, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "installed":
+			if f := strings.Fields(value); len(f) > 0 {
+				v.Installed = f[0]
+			}
+		case key == "channels":
+			inChannels = true
+		case inChannels && strings.HasPrefix(key, " "):
+			// "  latest/stable:    0.10.0 2023-01-01 (123) 5MB classic", closed channels show ^ or --
+			if f := strings.Fields(value); v.Available == "" && len(f) > 0 && f[0] != "^" && f[0] != "--" {
+				v.Available = f[0]
+			}
+		default:
+			inChannels = false
+		}
+	}
+	return v
+}
+
+// parseDnfInfo reads the Version and Release fields of the installed and
+// available sections of 'dnf info' and 'yum info', dnf 4 and dnf 5 alike.
+func parseDnfInfo(out string) pkgVersions {
+	var v pkgVersions
+	var section *string
+	var version string
+	for line := range strings.SplitSeq(out, "\n") {
+		lower := strings.ToLower(strings.TrimSpace(line))
+		if !strings.Contains(lower, ":") {
+			switch {
+			case strings.HasPrefix(lower, "installed"):
+				section = &v.Installed
+			case strings.HasPrefix(lower, "available"):
+				section = &v.Available
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(line, ":")
+		switch strings.TrimSpace(key) {
+		case "Version":
+			version = strings.TrimSpace(value)
+		case "Release":
+			if section != nil {
+				// the newest one is listed last
+				*section = version + "-" + strings.TrimSpace(value)
+			}
+		}
+	}
+	return v
+}
+
+// parseApkList reads 'apk list' lines like "fd-10.2.0-r0 x86_64 {fd} (MIT) [installed]".
+func parseApkList(out, pkg string) pkgVersions {
+	var v pkgVersions
+	for line := range strings.SplitSeq(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		version, ok := strings.CutPrefix(f[0], pkg+"-")
+		// skip the packages whose names only start with pkg, like fd-doc
+		if !ok || version == "" || version[0] < '0' || version[0] > '9' {
+			continue
+		}
+		if v.Available == "" {
+			v.Available = version
+		}
+		if strings.Contains(line, "[installed]") {
+			v.Installed = version
+		}
+	}
+	return v
+}
+
+// nixVersion reads the version of the first package of 'nix-env --json'.
+func nixVersion(out string) string {
+	var pkgs map[string]struct {
+		Version string `json:"version"`
+	}
+	if json.Unmarshal([]byte(out), &pkgs) != nil {
+		return ""
+	}
+	for _, p := range pkgs {
+		return p.Version
+	}
+	return ""
+}
+
+// printWhere asks every detected package manager, in parallel, which version
+// of pkg it has and which one is installed, and prints them as a table.
+func printWhere(pkg string) {
+	results := make([]pkgVersions, len(detectedPMs))
+	var wg sync.WaitGroup
+	for i, p := range detectedPMs {
+		query, ok := versionQueries[p.Name]
+		if !ok {
+			results[i] = pkgVersions{Available: unknownVersion, Installed: unknownVersion}
+			continue
+		}
+		wg.Go(func() {
+			results[i] = query(nameIn(pkg, p.Name))
+		})
+	}
+	wg.Wait()
+
+	recorded := installedWith(pkg)
+	installed := false
+
+	fmt.Printf("versions of '%s':\n", pkg)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  MANAGER\tNAME\tAVAILABLE\tINSTALLED")
+	for i, p := range detectedPMs {
+		v := results[i]
+		mark := " "
+		if v.Installed != "" && v.Installed != unknownVersion {
+			mark = "*"
+			installed = true
+		}
+		name := p.Name
+		if p.Name == pm.Name {
+			name += " (primary)"
+		}
+		note := ""
+		if p.Name == recorded {
+			note = "  (installed with 'i')"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%s\t%s%s\n", mark, name, nameIn(pkg, p.Name), orNone(v.Available), orNone(v.Installed), note)
+	}
+	w.Flush()
+
+	if installed {
+		fmt.Println("* installed")
+	}
+}
+
+func orNone(version string) string {
+	if version == "" {
+		return "-"
+	}
+	return version
+}